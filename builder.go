@@ -0,0 +1,334 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blakesmith/ar"
+)
+
+// Maintainer scripts, which are executable
+var builderScripts = []string{"preinst", "postinst", "prerm", "postrm", "config"}
+
+// Files of the control archive written by the builder itself
+var builderReserved = []string{"control", "md5sums", "conffiles"}
+
+// File of the data archive of a built package
+type builderFile struct {
+	header tar.Header
+	data   []byte
+}
+
+// PackageBuilder creates binary packages in deb or ipk format out of
+// control fields, maintainer scripts and files.
+type PackageBuilder struct {
+	control   *Paragraph
+	members   map[string]string
+	conffiles []string
+	files     map[string]*builderFile
+	modTime   time.Time
+	err       error
+}
+
+// NewPackageBuilder constructor. Files are dated with the current time.
+func NewPackageBuilder() *PackageBuilder {
+	pb := new(PackageBuilder)
+	pb.control = NewParagraph()
+	pb.members = make(map[string]string)
+	pb.conffiles = make([]string, 0)
+	pb.files = make(map[string]*builderFile)
+	pb.modTime = time.Now()
+	return pb
+}
+
+// SetField sets a field of the control file, e.g. "Package". Continuation
+// lines of multiline values have no leading space.
+func (pb *PackageBuilder) SetField(name string, value string) *PackageBuilder {
+	pb.control.Set(name, value)
+	return pb
+}
+
+// SetControlFile sets a file of the control archive other than control,
+// md5sums and conffiles, e.g. "postinst" or "triggers". Maintainer scripts
+// are executable. Other names are an error returned on writing.
+func (pb *PackageBuilder) SetControlFile(name string, content string) *PackageBuilder {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		pb.setErr(fmt.Errorf("invalid control archive file name '%s'", name))
+		return pb
+	}
+	for _, reserved := range builderReserved {
+		if name == reserved {
+			pb.setErr(fmt.Errorf("control archive file %s is written by the builder", name))
+			return pb
+		}
+	}
+	pb.members[name] = content
+	return pb
+}
+
+// Keep the first error of a setter, returned on writing
+func (pb *PackageBuilder) setErr(err error) {
+	if pb.err == nil {
+		pb.err = err
+	}
+}
+
+// SetModTime sets the modification time of all files, e.g. for reproducible builds.
+func (pb *PackageBuilder) SetModTime(modTime time.Time) *PackageBuilder {
+	pb.modTime = modTime
+	return pb
+}
+
+// Add an entry of the data archive. Missing parent directories are added.
+func (pb *PackageBuilder) add(name string, typeflag byte, mode os.FileMode, linkname string, data []byte) *PackageBuilder {
+	name = "." + path.Clean("/"+name)
+	if typeflag == tar.TypeDir {
+		name += "/"
+	}
+	pb.files[name] = &builderFile{header: tar.Header{
+		Name:     name,
+		Typeflag: typeflag,
+		Mode:     int64(mode.Perm()) | modeBits(mode),
+		Size:     int64(len(data)),
+		Linkname: linkname,
+		Uname:    "root",
+		Gname:    "root",
+	}, data: data}
+
+	if dir := path.Dir(strings.TrimSuffix(name, "/")); dir != "." {
+		if _, ok := pb.files[dir+"/"]; !ok {
+			pb.add(dir, tar.TypeDir, 0755, "", nil)
+		}
+	}
+	return pb
+}
+
+// Set-user-ID, set-group-ID and sticky bits of a file mode in tar format
+func modeBits(mode os.FileMode) int64 {
+	var bits int64
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+// AddFile adds a regular file with its content, e.g. "/usr/bin/hello".
+func (pb *PackageBuilder) AddFile(name string, mode os.FileMode, data []byte) *PackageBuilder {
+	return pb.add(name, tar.TypeReg, mode, "", data)
+}
+
+// AddConffile adds a regular file, which is a configuration file.
+func (pb *PackageBuilder) AddConffile(name string, mode os.FileMode, data []byte) *PackageBuilder {
+	if !pb.isConffile(name) {
+		pb.conffiles = append(pb.conffiles, path.Clean("/"+name))
+	}
+	return pb.add(name, tar.TypeReg, mode, "", data)
+}
+
+// AddDirectory adds a directory.
+func (pb *PackageBuilder) AddDirectory(name string, mode os.FileMode) *PackageBuilder {
+	return pb.add(name, tar.TypeDir, mode, "", nil)
+}
+
+// AddSymlink adds a symbolic link to a target.
+func (pb *PackageBuilder) AddSymlink(name string, target string) *PackageBuilder {
+	return pb.add(name, tar.TypeSymlink, 0777, target, nil)
+}
+
+// Entries of the data archive, sorted by path
+func (pb *PackageBuilder) entries() []*builderFile {
+	names := make([]string, 0, len(pb.files))
+	for name := range pb.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := []*builderFile{{header: tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755, Uname: "root", Gname: "root"}}}
+	for _, name := range names {
+		entries = append(entries, pb.files[name])
+	}
+	return entries
+}
+
+// Check required fields and add Installed-Size, unless it is set
+func (pb *PackageBuilder) controlFile() (string, error) {
+	for _, name := range []string{"Package", "Version", "Architecture"} {
+		if pb.control.Get(name) == "" {
+			return "", fmt.Errorf("control field %s is required", name)
+		}
+	}
+	if _, err := ParseVersion(pb.control.Get("Version")); err != nil {
+		return "", err
+	}
+	control := NewParagraph()
+	for _, name := range pb.control.Names() {
+		control.Set(name, pb.control.Get(name))
+	}
+	if !control.Has("Installed-Size") {
+		var size int64
+		for _, bf := range pb.files {
+			switch bf.header.Typeflag {
+			case tar.TypeReg:
+				size += (bf.header.Size + 1023) / 1024
+			case tar.TypeSymlink:
+				size += (int64(len(bf.header.Linkname)) + 1023) / 1024
+			default:
+				size++
+			}
+		}
+		control.Set("Installed-Size", strconv.FormatInt(size, 10))
+	}
+	return control.String(), nil
+}
+
+// Write a gzipped tar archive of entries
+func (pb *PackageBuilder) writeTarGz(w io.Writer, entries []*builderFile) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	for _, bf := range entries {
+		hdr := bf.header
+		hdr.ModTime = pb.modTime
+		hdr.Format = tar.FormatGNU
+		if err := tw.WriteHeader(&hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(bf.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// Control archive. The md5sums file is written for deb packages only.
+func (pb *PackageBuilder) controlArchive(md5sums bool) ([]byte, error) {
+	control, err := pb.controlFile()
+	if err != nil {
+		return nil, err
+	}
+	entries := []*builderFile{
+		{header: tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755}},
+		{header: tar.Header{Name: "./control", Mode: 0644}, data: []byte(control)},
+	}
+	var sums strings.Builder
+	for _, bf := range pb.entries() {
+		if md5sums && bf.header.Typeflag == tar.TypeReg && !pb.isConffile(bf.header.Name) {
+			sum := md5.Sum(bf.data)
+			sums.WriteString(fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), strings.TrimPrefix(bf.header.Name, "./")))
+		}
+	}
+	if sums.Len() > 0 {
+		entries = append(entries, &builderFile{header: tar.Header{Name: "./md5sums", Mode: 0644}, data: []byte(sums.String())})
+	}
+	if len(pb.conffiles) > 0 {
+		entries = append(entries, &builderFile{header: tar.Header{Name: "./conffiles", Mode: 0644}, data: []byte(strings.Join(pb.conffiles, "\n") + "\n")})
+	}
+
+	names := make([]string, 0, len(pb.members))
+	for name := range pb.members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mode := int64(0644)
+		for _, script := range builderScripts {
+			if name == script {
+				mode = 0755
+			}
+		}
+		entries = append(entries, &builderFile{header: tar.Header{Name: "./" + name, Mode: mode}, data: []byte(pb.members[name])})
+	}
+	for _, bf := range entries {
+		bf.header.Uname, bf.header.Gname, bf.header.Size = "root", "root", int64(len(bf.data))
+		if bf.header.Typeflag == 0 {
+			bf.header.Typeflag = tar.TypeReg
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pb.writeTarGz(&buf, entries); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Check if a path of the data archive is a conffile
+func (pb *PackageBuilder) isConffile(name string) bool {
+	for _, cf := range pb.conffiles {
+		if cf == absPath(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Package members: debian-binary, control and data archives
+func (pb *PackageBuilder) archives(md5sums bool) ([]string, [][]byte, error) {
+	if pb.err != nil {
+		return nil, nil, pb.err
+	}
+	control, err := pb.controlArchive(md5sums)
+	if err != nil {
+		return nil, nil, err
+	}
+	var data bytes.Buffer
+	if err := pb.writeTarGz(&data, pb.entries()); err != nil {
+		return nil, nil, err
+	}
+	return []string{"debian-binary", "control.tar.gz", "data.tar.gz"}, [][]byte{[]byte("2.0\n"), control, data.Bytes()}, nil
+}
+
+// WriteDeb writes a Debian package, an ar archive.
+func (pb *PackageBuilder) WriteDeb(w io.Writer) error {
+	names, members, err := pb.archives(true)
+	if err != nil {
+		return err
+	}
+	aw := ar.NewWriter(w)
+	if err := aw.WriteGlobalHeader(); err != nil {
+		return err
+	}
+	for idx, name := range names {
+		hdr := &ar.Header{Name: name, ModTime: pb.modTime, Mode: 0100644, Size: int64(len(members[idx]))}
+		if err := aw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := aw.Write(members[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteIPK writes an opkg package, a gzipped tar archive as created by
+// opkg-build.
+func (pb *PackageBuilder) WriteIPK(w io.Writer) error {
+	names, members, err := pb.archives(false)
+	if err != nil {
+		return err
+	}
+	entries := make([]*builderFile, 0)
+	for idx, name := range names {
+		entries = append(entries, &builderFile{header: tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0644,
+			Size: int64(len(members[idx])), Uname: "root", Gname: "root"}, data: members[idx]})
+	}
+	return pb.writeTarGz(w, entries)
+}
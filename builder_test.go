@@ -0,0 +1,145 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Package with a binary, a conffile, a symlink and a maintainer script
+func testBuilder() *PackageBuilder {
+	return NewPackageBuilder().
+		SetField("Package", "hello").
+		SetField("Version", "1.0-1").
+		SetField("Architecture", "amd64").
+		SetField("Maintainer", "Jane Doe <jane@example.org>").
+		SetField("Depends", "libc6 (>= 2.34)").
+		SetField("Description", "greeting\nPrints a greeting.").
+		SetControlFile("postinst", "#!/bin/sh\nexit 0\n").
+		SetModTime(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)).
+		AddFile("/usr/bin/hello", 0755, []byte("#!/bin/sh\necho hello\n")).
+		AddConffile("/etc/hello.conf", 0644, []byte("greeting=hello\n")).
+		AddSymlink("/usr/bin/hi", "hello")
+}
+
+// Build a package and read it back
+func testRoundTrip(t *testing.T, write func(*PackageBuilder, io.Writer) error) *PackageFile {
+	var buf bytes.Buffer
+	if err := write(testBuilder(), &buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	pf, err := NewPackageFileReader(bytes.NewReader(buf.Bytes())).SetMetaonly(false).SetHash(HASH_MD5).Read()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return pf
+}
+
+// Check fields, scripts, conffiles and files of the test package
+func checkRoundTrip(t *testing.T, pf *PackageFile) {
+	cf := pf.ControlFile()
+	if cf.Package() != "hello" || cf.Version() != "1.0-1" || cf.Architecture() != "amd64" {
+		t.Errorf("package %s %s [%s]", cf.Package(), cf.Version(), cf.Architecture())
+	}
+	if cf.Maintainer() != "Jane Doe <jane@example.org>" {
+		t.Errorf("maintainer %q", cf.Maintainer())
+	}
+	if deps := cf.Depends(); len(deps) != 1 || deps[0] != "libc6 (>= 2.34)" {
+		t.Errorf("depends %q", deps)
+	}
+	if cf.InstalledSize() == 0 {
+		t.Errorf("no installed size")
+	}
+	if pf.PostInstallScript() != "#!/bin/sh\nexit 0\n" {
+		t.Errorf("postinst %q", pf.PostInstallScript())
+	}
+	if names := pf.ConffilesFile().Names(); len(names) != 1 || names[0] != "/etc/hello.conf" {
+		t.Errorf("conffiles %q", names)
+	}
+	found := false
+	for _, fi := range pf.Files() {
+		if absPath(fi.Name()) == "/usr/bin/hi" {
+			found = fi.Linkname() == "hello"
+		}
+	}
+	if !found {
+		t.Errorf("symlink /usr/bin/hi -> hello is missing")
+	}
+	if errs := pf.CheckConffiles(); len(errs) != 0 {
+		t.Errorf("conffiles: %v", errs)
+	}
+}
+
+func TestBuilderDebRoundTrip(t *testing.T) {
+	pf := testRoundTrip(t, (*PackageBuilder).WriteDeb)
+	if pf.Format() != PACKAGE_FORMAT_DEB {
+		t.Errorf("format %q", pf.Format())
+	}
+	if pf.DebVersion() != "2.0" {
+		t.Errorf("deb version %q", pf.DebVersion())
+	}
+	checkRoundTrip(t, pf)
+
+	sum := md5.Sum([]byte("#!/bin/sh\necho hello\n"))
+	if got := pf.GetFileMd5Sums("usr/bin/hello"); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("md5sums of /usr/bin/hello %q", got)
+	}
+	if got := pf.GetFileMd5Sums("etc/hello.conf"); got != "" {
+		t.Errorf("conffile in md5sums: %q", got)
+	}
+	report, err := pf.VerifyIntegrity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("integrity check failed: %+v", report)
+	}
+}
+
+func TestBuilderIPKRoundTrip(t *testing.T) {
+	pf := testRoundTrip(t, (*PackageBuilder).WriteIPK)
+	if pf.Format() != PACKAGE_FORMAT_IPK {
+		t.Errorf("format %q", pf.Format())
+	}
+	checkRoundTrip(t, pf)
+}
+
+func TestBuilderReproducible(t *testing.T) {
+	var a, b bytes.Buffer
+	if err := testBuilder().WriteDeb(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := testBuilder().WriteDeb(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("packages with the same content and time differ")
+	}
+}
+
+func TestBuilderReservedControlFiles(t *testing.T) {
+	for _, name := range []string{"control", "md5sums", "conffiles", "", "..", "../postinst", "scripts/postinst"} {
+		pb := testBuilder().SetControlFile(name, "Package: evil\n")
+		if err := pb.WriteDeb(io.Discard); err == nil {
+			t.Errorf("deb with control archive file %q written", name)
+		}
+		if err := pb.WriteIPK(io.Discard); err == nil {
+			t.Errorf("ipk with control archive file %q written", name)
+		}
+	}
+}
+
+func TestBuilderRequiredFields(t *testing.T) {
+	err := NewPackageBuilder().SetField("Package", "hello").WriteDeb(io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "Version") {
+		t.Errorf("missing Version: %v", err)
+	}
+	err = NewPackageBuilder().SetField("Package", "hello").SetField("Version", "a:1").SetField("Architecture", "all").WriteDeb(io.Discard)
+	if err == nil {
+		t.Errorf("invalid version accepted")
+	}
+}
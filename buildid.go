@@ -0,0 +1,175 @@
+package deb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of objects found by build ID
+const (
+	BUILD_ID_DEBUGINFO  = "debuginfo"
+	BUILD_ID_EXECUTABLE = "executable"
+)
+
+// BuildIdEntry is an object with a GNU build ID, shipped in a package.
+type BuildIdEntry struct {
+	BuildId      string `json:"build_id"`
+	Kind         string `json:"kind"`
+	Package      string `json:"package"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture"`
+	// Absolute path of the object in the package
+	Path string `json:"path"`
+	// Path of the package file
+	PackagePath string `json:"package_path"`
+}
+
+// BuildIdIndex maps GNU build IDs to executables and debug symbols in
+// packages. It is safe for concurrent use.
+type BuildIdIndex struct {
+	entries map[string][]*BuildIdEntry
+	mu      sync.RWMutex
+}
+
+// NewBuildIdIndex constructor
+func NewBuildIdIndex() *BuildIdIndex {
+	bi := new(BuildIdIndex)
+	bi.entries = make(map[string][]*BuildIdEntry)
+	return bi
+}
+
+// Add an entry, unless the object is already known
+func (bi *BuildIdIndex) add(entry *BuildIdEntry) {
+	for _, known := range bi.entries[entry.BuildId] {
+		if known.Kind == entry.Kind && known.PackagePath == entry.PackagePath && known.Path == entry.Path {
+			return
+		}
+	}
+	bi.entries[entry.BuildId] = append(bi.entries[entry.BuildId], entry)
+}
+
+// Add indexes ELF objects with a build ID of a package, which must be read
+// with files. Objects in DEBUG_BUILD_ID_DIR are debug symbols, and
+// unstripped objects are both executables and debug symbols. Build-Ids of
+// debug symbols packages are indexed even if only meta-data was read.
+func (bi *BuildIdIndex) Add(pf *PackageFile) *BuildIdIndex {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	cf := pf.ControlFile()
+	entry := func(id string, kind string, path string) *BuildIdEntry {
+		return &BuildIdEntry{BuildId: id, Kind: kind, Package: cf.Package(), Version: cf.Version(),
+			Architecture: cf.Architecture(), Path: path, PackagePath: pf.Path()}
+	}
+	for _, ef := range pf.ElfFiles() {
+		if ef.BuildID() == "" {
+			continue
+		}
+		path := absPath(ef.Path())
+		if strings.HasPrefix(path, DEBUG_BUILD_ID_DIR+"/") {
+			bi.add(entry(ef.BuildID(), BUILD_ID_DEBUGINFO, path))
+			continue
+		}
+		bi.add(entry(ef.BuildID(), BUILD_ID_EXECUTABLE, path))
+		if ef.HasDebugInfo() {
+			bi.add(entry(ef.BuildID(), BUILD_ID_DEBUGINFO, path))
+		}
+	}
+	for id, path := range pf.DebugFiles() {
+		bi.add(entry(id, BUILD_ID_DEBUGINFO, path))
+	}
+	return bi
+}
+
+// Merge adds entries of another index.
+func (bi *BuildIdIndex) Merge(other *BuildIdIndex) *BuildIdIndex {
+	for _, id := range other.BuildIds() {
+		entries := other.Lookup(id)
+		bi.mu.Lock()
+		for _, entry := range entries {
+			bi.add(entry)
+		}
+		bi.mu.Unlock()
+	}
+	return bi
+}
+
+// Lookup returns objects of a build ID, or nil if it is unknown.
+func (bi *BuildIdIndex) Lookup(id string) []*BuildIdEntry {
+	bi.mu.RLock()
+	defer bi.mu.RUnlock()
+	return bi.entries[strings.ToLower(id)]
+}
+
+// Find returns the first object of a build ID of a kind, BUILD_ID_DEBUGINFO
+// or BUILD_ID_EXECUTABLE, or nil if there is none.
+func (bi *BuildIdIndex) Find(id string, kind string) *BuildIdEntry {
+	for _, entry := range bi.Lookup(id) {
+		if entry.Kind == kind {
+			return entry
+		}
+	}
+	return nil
+}
+
+// BuildIds returns all known build IDs, sorted.
+func (bi *BuildIdIndex) BuildIds() []string {
+	bi.mu.RLock()
+	defer bi.mu.RUnlock()
+	ids := make([]string, 0, len(bi.entries))
+	for id := range bi.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// WriteJSON writes all entries as a JSON array, sorted by build ID.
+func (bi *BuildIdIndex) WriteJSON(w io.Writer) error {
+	entries := make([]*BuildIdEntry, 0)
+	for _, id := range bi.BuildIds() {
+		entries = append(entries, bi.Lookup(id)...)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// ReadBuildIdIndex reads an index written by WriteJSON.
+func ReadBuildIdIndex(r io.Reader) (*BuildIdIndex, error) {
+	entries := make([]*BuildIdEntry, 0)
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	bi := NewBuildIdIndex()
+	for _, entry := range entries {
+		entry.BuildId = strings.ToLower(entry.BuildId)
+		bi.add(entry)
+	}
+	return bi, nil
+}
+
+// BuildIdIndexFromDir indexes all .deb and .ddeb files in a directory and
+// its subdirectories. Paths of packages are relative to the current
+// directory, as given by the directory. Packages which cannot be read are
+// skipped and returned with their errors.
+func BuildIdIndexFromDir(ctx context.Context, dir string) (*BuildIdIndex, []ScanResult, error) {
+	bi := NewBuildIdIndex()
+	skipped := make([]ScanResult, 0)
+	scanner := NewScanner().SetSuffixes(".deb", ".ddeb").SetOptions(&PackageOptions{Hash: HASH_MD5})
+	for res := range scanner.ScanDir(ctx, dir) {
+		if res.Err != nil {
+			skipped = append(skipped, res)
+			continue
+		}
+		bi.Add(res.Package)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return bi, skipped, nil
+}
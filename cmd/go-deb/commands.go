@@ -0,0 +1,391 @@
+package main
+
+import (
+	"archive/tar"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	deb "github.com/isbm/go-deb"
+)
+
+// Open a package, reading files of the data archive unless only meta-data is needed
+func openPackage(uri string, metaonly bool, hash int) (*deb.PackageFile, error) {
+	return deb.OpenPackageFile(uri, &deb.PackageOptions{MetaOnly: metaonly, Hash: hash, RecalculateChecksums: !metaonly})
+}
+
+func infoCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, 1); err != nil {
+		return err
+	}
+	pf, err := openPackage(flags.Arg(0), true, deb.HASH_MD5)
+	if err != nil {
+		return err
+	}
+	cf := pf.ControlFile()
+
+	if asJSON {
+		fields := make(map[string]string)
+		for _, name := range cf.Fields() {
+			fields[name] = cf.Field(name)
+		}
+		return printJSON(map[string]interface{}{
+			"path":           pf.Path(),
+			"size":           pf.FileSize(),
+			"format":         pf.DebVersion(),
+			"package_format": pf.Format(),
+			"package_type":   pf.PackageType(),
+			"control":        fields,
+			"conffiles":      pf.ConffilesFile().Names(),
+			"has_templates":  len(pf.TemplatesFile().Templates()) > 0,
+		})
+	}
+
+	if pf.Format() == deb.PACKAGE_FORMAT_IPK {
+		fmt.Printf(" new opkg package, version %s.\n", pf.DebVersion())
+	} else {
+		fmt.Printf(" new Debian %s package, version %s.\n", pf.PackageType(), pf.DebVersion())
+	}
+	fmt.Printf(" size %d bytes.\n", pf.FileSize())
+	for _, name := range cf.Fields() {
+		fmt.Printf(" %s: %s\n", name, strings.Replace(foldValue(cf.Field(name)), "\n", "\n ", -1))
+	}
+	if names := pf.ConffilesFile().Names(); len(names) > 0 {
+		fmt.Printf(" Conffiles:\n")
+		for _, name := range names {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	return nil
+}
+
+// Mode of a file as shown by ls and dpkg -c
+func modeString(fi *deb.FileInfo) string {
+	mode := fi.Mode()
+	kind := byte('-')
+	switch {
+	case mode.IsDir():
+		kind = 'd'
+	case mode&os.ModeSymlink != 0:
+		kind = 'l'
+	case mode&os.ModeCharDevice != 0:
+		kind = 'c'
+	case mode&os.ModeDevice != 0:
+		kind = 'b'
+	case mode&os.ModeNamedPipe != 0:
+		kind = 'p'
+	}
+	perm := []byte(mode.Perm().String())
+	perm[0] = kind
+	special := []struct {
+		flag os.FileMode
+		pos  int
+		set  byte
+	}{{os.ModeSetuid, 3, 's'}, {os.ModeSetgid, 6, 's'}, {os.ModeSticky, 9, 't'}}
+	for _, s := range special {
+		if mode&s.flag == 0 {
+			continue
+		}
+		if perm[s.pos] == 'x' {
+			perm[s.pos] = s.set
+		} else {
+			perm[s.pos] = s.set - 'a' + 'A'
+		}
+	}
+	return string(perm)
+}
+
+func contentsCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, 1); err != nil {
+		return err
+	}
+	pf, err := openPackage(flags.Arg(0), false, deb.HASH_MD5)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		type entry struct {
+			Path     string `json:"path"`
+			Mode     string `json:"mode"`
+			Owner    string `json:"owner"`
+			Group    string `json:"group"`
+			Size     int64  `json:"size"`
+			Time     string `json:"mtime"`
+			Linkname string `json:"link,omitempty"`
+		}
+		entries := make([]entry, 0)
+		for _, fi := range pf.Files() {
+			entries = append(entries, entry{Path: fi.Name(), Mode: modeString(&fi), Owner: fi.Owner(), Group: fi.Group(),
+				Size: fi.Size(), Time: fi.ModTime().UTC().Format("2006-01-02T15:04:05Z"), Linkname: fi.Linkname()})
+		}
+		return printJSON(entries)
+	}
+
+	for _, fi := range pf.Files() {
+		line := fmt.Sprintf("%s %s/%s %9d %s %s", modeString(&fi), fi.Owner(), fi.Group(), fi.Size(),
+			fi.ModTime().Format("2006-01-02 15:04"), fi.Name())
+		if fi.Linkname() != "" {
+			if fi.Mode()&os.ModeSymlink != 0 {
+				line += " -> " + fi.Linkname()
+			} else {
+				line += " link to " + fi.Linkname()
+			}
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func scriptsCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, 2); err != nil {
+		return err
+	}
+	pf, err := openPackage(flags.Arg(0), true, deb.HASH_MD5)
+	if err != nil {
+		return err
+	}
+
+	names := []string{"preinst", "postinst", "prerm", "postrm", "config"}
+	scripts := map[string]string{
+		"preinst":  pf.PreInstallScript(),
+		"postinst": pf.PostInstallScript(),
+		"prerm":    pf.PreUninstallScript(),
+		"postrm":   pf.PostUninstallScript(),
+		"config":   pf.ConfigScript(),
+	}
+	if flags.NArg() == 2 {
+		name := flags.Arg(1)
+		if _, ok := scripts[name]; !ok {
+			return fmt.Errorf("unknown script '%s', expected one of: %s", name, strings.Join(names, ", "))
+		}
+		names = []string{name}
+	}
+
+	if asJSON {
+		found := make(map[string]string)
+		for _, name := range names {
+			if scripts[name] != "" {
+				found[name] = scripts[name]
+			}
+		}
+		return printJSON(found)
+	}
+
+	if len(names) == 1 {
+		if scripts[names[0]] == "" {
+			return fmt.Errorf("package has no %s script", names[0])
+		}
+		fmt.Print(scripts[names[0]])
+		return nil
+	}
+	for _, name := range names {
+		if scripts[name] == "" {
+			continue
+		}
+		fmt.Printf("### %s\n%s", name, scripts[name])
+		if !strings.HasSuffix(scripts[name], "\n") {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+var checksumHash *string
+
+func checksumsFlags(flags *flag.FlagSet) {
+	checksumHash = flags.String("hash", "md5", "hash type: md5, sha1, sha256, sha512 or blake2b")
+}
+
+func checksumsCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, 1); err != nil {
+		return err
+	}
+	hashes := map[string]int{"md5": deb.HASH_MD5, "sha1": deb.HASH_SHA1, "sha256": deb.HASH_SHA256,
+		"sha512": deb.HASH_SHA512, "blake2b": deb.HASH_BLAKE2B}
+	hash, ok := hashes[strings.ToLower(*checksumHash)]
+	if !ok {
+		return fmt.Errorf("unknown hash type '%s'", *checksumHash)
+	}
+	pf, err := openPackage(flags.Arg(0), false, hash)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		Path     string `json:"path"`
+		Checksum string `json:"checksum"`
+		Md5sum   string `json:"md5sums,omitempty"`
+	}
+	entries := make([]entry, 0)
+	for _, fi := range pf.Files() {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		entries = append(entries, entry{Path: fi.Name(), Checksum: pf.GetCalculatedChecksum(fi.Name()), Md5sum: pf.GetFileMd5Sums(fi.Name())})
+	}
+	if asJSON {
+		return printJSON(entries)
+	}
+	for _, e := range entries {
+		fmt.Printf("%s  %s\n", e.Checksum, strings.TrimPrefix(e.Path, "./"))
+	}
+	return nil
+}
+
+// Resolve path of an archive entry within the target directory, refusing to escape it
+func targetPath(dir string, name string) string {
+	return filepath.Join(dir, filepath.Clean(string(filepath.Separator)+filepath.FromSlash(name)))
+}
+
+// Check that a path does not escape the target directory through symbolic
+// links, which were extracted earlier. The deepest existing ancestor of the
+// path, or the path itself, is resolved.
+func checkTarget(root string, target string) error {
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil || filepath.Dir(existing) == existing {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s: refusing to write outside of %s", target, root)
+	}
+	return nil
+}
+
+func extractCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 2, 2); err != nil {
+		return err
+	}
+	dir := flags.Arg(1)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	uri := flags.Arg(0)
+	if strings.Contains(uri, "://") {
+		return fmt.Errorf("extracting from URLs is not supported, download the package first")
+	}
+	f, err := os.Open(uri)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	root, err := filepath.Abs(dir)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return err
+	}
+
+	extracted := make([]string, 0)
+	handler := func(hdr *tar.Header, content io.Reader) error {
+		target := targetPath(root, hdr.Name)
+		mode := hdr.FileInfo().Mode()
+		if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 && hdr.Typeflag != tar.TypeDir {
+			os.Remove(target) // Replaced, not followed
+		}
+		if err := checkTarget(root, target); err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, content); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source := targetPath(root, hdr.Linkname)
+			if err := checkTarget(root, source); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "go-deb: skipping special file %s\n", hdr.Name)
+			return nil
+		}
+		os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		extracted = append(extracted, hdr.Name)
+		return nil
+	}
+
+	if _, err := deb.NewPackageFileReader(f).SetMetaonly(false).SetFileHandler(handler).Read(); err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(map[string]interface{}{"directory": dir, "files": extracted})
+	}
+	return nil
+}
+
+func fieldCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, -1); err != nil {
+		return err
+	}
+	pf, err := openPackage(flags.Arg(0), true, deb.HASH_MD5)
+	if err != nil {
+		return err
+	}
+	cf := pf.ControlFile()
+
+	names := flags.Args()[1:]
+	if len(names) == 0 {
+		names = cf.Fields()
+	}
+	if asJSON {
+		fields := make(map[string]string)
+		for _, name := range names {
+			if value := cf.Field(name); value != "" {
+				fields[name] = value
+			}
+		}
+		return printJSON(fields)
+	}
+	if len(names) == 1 && flags.NArg() == 2 {
+		fmt.Println(foldValue(cf.Field(names[0])))
+		return nil
+	}
+	for _, name := range names {
+		if value := cf.Field(name); value != "" {
+			fmt.Printf("%s: %s\n", name, foldValue(value))
+		}
+	}
+	return nil
+}
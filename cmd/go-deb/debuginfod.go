@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	deb "github.com/isbm/go-deb"
+)
+
+// Options of the debuginfod command
+var debuginfodOpts struct {
+	listen *string
+	list   *bool
+}
+
+func debuginfodFlags(flags *flag.FlagSet) {
+	debuginfodOpts.listen = flags.String("listen", ":8002", "address to serve the debuginfod API on")
+	debuginfodOpts.list = flags.Bool("list", false, "list indexed build IDs instead of serving them")
+}
+
+func debuginfodCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, -1); err != nil {
+		return err
+	}
+	index := deb.NewBuildIdIndex()
+	for _, dir := range flags.Args() {
+		bi, skipped, err := deb.BuildIdIndexFromDir(context.Background(), dir)
+		if err != nil {
+			return err
+		}
+		for _, res := range skipped {
+			fmt.Fprintf(os.Stderr, "go-deb: skipping %s: %s\n", res.Path, res.Err.Error())
+		}
+		index.Merge(bi)
+	}
+
+	if *debuginfodOpts.list {
+		if asJSON {
+			return index.WriteJSON(os.Stdout)
+		}
+		for _, id := range index.BuildIds() {
+			for _, entry := range index.Lookup(id) {
+				fmt.Printf("%s %-10s %s %s %s %s\n", id, entry.Kind, entry.Package, entry.Version, entry.Path, entry.PackagePath)
+			}
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "serving %d build IDs on %s\n", len(index.BuildIds()), *debuginfodOpts.listen)
+	return http.ListenAndServe(*debuginfodOpts.listen, deb.NewDebuginfodServer(index))
+}
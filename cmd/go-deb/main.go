@@ -0,0 +1,138 @@
+// Command go-deb inspects Debian packages without dpkg.
+//
+// Usage:
+//
+//	go-deb <command> [-json] <package.deb> [arguments]
+//
+// Commands:
+//
+//	info       show control fields and package file details
+//	contents   list files of the data archive, like "dpkg -c"
+//	scripts    show maintainer scripts, or a single one by its name
+//	checksums  show checksums of the packaged files
+//	extract    extract the data archive to a directory
+//	field      show values of the control fields, like "dpkg-deb -f"
+//	verify     check md5sums and, with a keyring, the signed repository index
+//	sbom       export a software bill of materials as SPDX or CycloneDX
+//	vulns      match packages against local Debian security data
+//	debuginfod serve debug symbols of packages in directories by build ID
+//	repo       build and publish APT repositories: init, add, remove, index, sign
+//
+// Packages are paths, or file and http(s) URLs.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Subcommand runs with its flag set, which is already parsed. Commands
+// grouping other commands have subcommands instead.
+type command struct {
+	usage       string
+	help        string
+	run         func(flags *flag.FlagSet, asJSON bool) error
+	flags       func(flags *flag.FlagSet)
+	subcommands map[string]*command
+	order       []string
+}
+
+var root = &command{
+	usage: "[-json] <package> [arguments]",
+	subcommands: map[string]*command{
+		"info":       {usage: "<package>", help: "show control fields and package file details", run: infoCmd},
+		"contents":   {usage: "<package>", help: "list files of the data archive", run: contentsCmd},
+		"scripts":    {usage: "<package> [script]", help: "show maintainer scripts", run: scriptsCmd},
+		"checksums":  {usage: "[-hash md5|sha1|sha256|sha512|blake2b] <package>", help: "show checksums of the packaged files", run: checksumsCmd, flags: checksumsFlags},
+		"extract":    {usage: "<package> <directory>", help: "extract the data archive to a directory", run: extractCmd},
+		"field":      {usage: "<package> [field...]", help: "show values of control fields", run: fieldCmd},
+		"verify":     {usage: "[-keyring file -suite dir -index path] <package>", help: "check md5sums and the signed repository index", run: verifyCmd, flags: verifyFlags},
+		"sbom":       {usage: "[-format spdx-json|spdx|cyclonedx] [-status file] [-root dir] [package...]", help: "export a software bill of materials", run: sbomCmd, flags: sbomFlags},
+		"vulns":      {usage: "-release codename [-tracker file] [-oval file] [-dsa file] [-status file] [-index file] [package...]", help: "match packages against local Debian security data", run: vulnsCmd, flags: vulnsFlags},
+		"debuginfod": {usage: "[-listen address] [-list] <directory...>", help: "serve debug symbols of packages by build ID", run: debuginfodCmd, flags: debuginfodFlags},
+		"repo":       repoCommand,
+	},
+	order: []string{"info", "contents", "scripts", "checksums", "extract", "field", "verify", "sbom", "vulns", "debuginfod", "repo"},
+}
+
+func usage(name string, cmd *command) {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> %s\n\nCommands:\n", name, cmd.usage)
+	for _, sub := range cmd.order {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", sub, cmd.subcommands[sub].help)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for help on a command.\n", name)
+}
+
+func main() {
+	name, cmd, args := "go-deb", root, os.Args[1:]
+	for cmd.subcommands != nil {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+			usage(name, cmd)
+			os.Exit(2)
+		}
+		sub, ok := cmd.subcommands[args[0]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: unknown command '%s'\n\n", name, args[0])
+			usage(name, cmd)
+			os.Exit(2)
+		}
+		name, cmd, args = name+" "+args[0], sub, args[1:]
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print output as JSON")
+	if cmd.flags != nil {
+		cmd.flags(flags)
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-json] %s\n\n%s\n\nOptions:\n", name, cmd.usage, cmd.help)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if err := run(cmd, flags, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "go-deb: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// Run a command, turning panics of the package reader into errors
+func run(cmd *command, flags *flag.FlagSet, asJSON bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return cmd.run(flags, asJSON)
+}
+
+// Print a value as indented JSON
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Check number of positional arguments
+func checkArgs(flags *flag.FlagSet, min int, max int) error {
+	if flags.NArg() < min || (max > -1 && flags.NArg() > max) {
+		flags.Usage()
+		return fmt.Errorf("wrong number of arguments")
+	}
+	return nil
+}
+
+// Indent continuation lines of a multiline field value
+func foldValue(value string) string {
+	lines := strings.Split(value, "\n")
+	for idx := 1; idx < len(lines); idx++ {
+		if strings.TrimSpace(lines[idx]) == "" {
+			lines[idx] = "."
+		}
+		lines[idx] = " " + lines[idx]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	deb "github.com/isbm/go-deb"
+)
+
+var repoCommand = &command{
+	usage: "[-json] [options] [arguments]",
+	help:  "build and publish APT repositories",
+	subcommands: map[string]*command{
+		"init": {usage: "[-suites s1,s2] [-components c1,c2] [-architectures a1,a2] [directory]",
+			help: "create a repository", run: repoInitCmd, flags: repoInitFlags},
+		"add": {usage: "[-root dir] [-suite suite] [-component component] <package>...",
+			help: "add packages to a suite", run: repoAddCmd, flags: repoTargetFlags},
+		"remove": {usage: "[-root dir] [-suite suite] [-component component] <name> [version]",
+			help: "remove a package from a suite", run: repoRemoveCmd, flags: repoTargetFlags},
+		"index": {usage: "[-root dir] [suite...]",
+			help: "generate Packages, Contents and Release files", run: repoIndexCmd, flags: repoRootFlags},
+		"sign": {usage: "[-root dir] -key file [-passphrase-file file] [suite...]",
+			help: "sign Release files", run: repoSignCmd, flags: repoSignFlags},
+	},
+	order: []string{"init", "add", "remove", "index", "sign"},
+}
+
+// Options of the repository commands
+var repoOpts struct {
+	root           *string
+	suite          *string
+	component      *string
+	origin         *string
+	label          *string
+	description    *string
+	codename       *string
+	suites         *string
+	components     *string
+	architectures  *string
+	key            *string
+	passphraseFile *string
+}
+
+func repoRootFlags(flags *flag.FlagSet) {
+	repoOpts.root = flags.String("root", ".", "repository directory")
+}
+
+func repoTargetFlags(flags *flag.FlagSet) {
+	repoRootFlags(flags)
+	repoOpts.suite = flags.String("suite", "", "suite, the first configured one by default")
+	repoOpts.component = flags.String("component", "", "component, the first of the suite by default")
+}
+
+func repoInitFlags(flags *flag.FlagSet) {
+	repoOpts.origin = flags.String("origin", "", "Origin field of Release files")
+	repoOpts.label = flags.String("label", "", "Label field of Release files")
+	repoOpts.description = flags.String("description", "", "Description field of Release files")
+	repoOpts.codename = flags.String("codename", "", "codename of the suite, if there is one suite")
+	repoOpts.suites = flags.String("suites", "stable", "comma separated suites")
+	repoOpts.components = flags.String("components", "main", "comma separated components of each suite")
+	repoOpts.architectures = flags.String("architectures", "amd64", "comma separated architectures of each suite")
+}
+
+func repoSignFlags(flags *flag.FlagSet) {
+	repoRootFlags(flags)
+	repoOpts.key = flags.String("key", "", "armored secret key file")
+	repoOpts.passphraseFile = flags.String("passphrase-file", "", "file with the passphrase of the key")
+}
+
+// Split comma separated list
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func repoInitCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 0, 1); err != nil {
+		return err
+	}
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	config := &deb.RepoConfig{Origin: *repoOpts.origin, Label: *repoOpts.label, Description: *repoOpts.description}
+	suites := splitList(*repoOpts.suites)
+	if *repoOpts.codename != "" && len(suites) != 1 {
+		return fmt.Errorf("codename can be only set for a single suite")
+	}
+	for _, name := range suites {
+		config.Suites = append(config.Suites, deb.RepoSuite{
+			Name:          name,
+			Codename:      *repoOpts.codename,
+			Components:    splitList(*repoOpts.components),
+			Architectures: splitList(*repoOpts.architectures),
+		})
+	}
+
+	repo, err := deb.InitRepository(dir, config)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(repo.Config())
+	}
+	fmt.Printf("Initialized repository in %s\n", dir)
+	return nil
+}
+
+// Open repository and resolve the target suite and component
+func openRepoTarget() (*deb.Repository, string, string, error) {
+	repo, err := deb.OpenRepository(*repoOpts.root)
+	if err != nil {
+		return nil, "", "", err
+	}
+	suite := repo.Config().Suite(*repoOpts.suite)
+	if *repoOpts.suite == "" && len(repo.Config().Suites) > 0 {
+		suite = &repo.Config().Suites[0]
+	}
+	if suite == nil {
+		return nil, "", "", fmt.Errorf("unknown suite '%s'", *repoOpts.suite)
+	}
+	component := *repoOpts.component
+	if component == "" && len(suite.Components) > 0 {
+		component = suite.Components[0]
+	}
+	return repo, suite.Name, component, nil
+}
+
+func repoAddCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, -1); err != nil {
+		return err
+	}
+	repo, suite, component, err := openRepoTarget()
+	if err != nil {
+		return err
+	}
+
+	added := make([]map[string]string, 0)
+	for _, path := range flags.Args() {
+		entry, err := repo.Add(suite, component, path)
+		if err != nil {
+			return err
+		}
+		if !asJSON {
+			fmt.Printf("Added %s %s [%s] to %s/%s\n", entry.Package(), entry.Version(), entry.Architecture(), suite, component)
+		}
+		added = append(added, map[string]string{
+			"package":      entry.Package(),
+			"version":      entry.Version(),
+			"architecture": entry.Architecture(),
+			"filename":     entry.Filename(),
+			"suite":        suite,
+			"component":    component,
+		})
+	}
+	if asJSON {
+		return printJSON(added)
+	}
+	return nil
+}
+
+func repoRemoveCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, 2); err != nil {
+		return err
+	}
+	repo, suite, component, err := openRepoTarget()
+	if err != nil {
+		return err
+	}
+	removed, err := repo.Remove(suite, component, flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(map[string]interface{}{"package": flags.Arg(0), "suite": suite, "component": component, "removed": removed})
+	}
+	if removed == 0 {
+		return fmt.Errorf("package %s is not in %s/%s", flags.Arg(0), suite, component)
+	}
+	fmt.Printf("Removed %s from %s/%s\n", flags.Arg(0), suite, component)
+	return nil
+}
+
+func repoIndexCmd(flags *flag.FlagSet, asJSON bool) error {
+	repo, err := deb.OpenRepository(*repoOpts.root)
+	if err != nil {
+		return err
+	}
+	if err := repo.Index(flags.Args()...); err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(map[string]interface{}{"indexed": indexedSuites(repo, flags.Args())})
+	}
+	return nil
+}
+
+// Names of suites given as arguments, or all of them
+func indexedSuites(repo *deb.Repository, args []string) []string {
+	if len(args) > 0 {
+		return args
+	}
+	names := make([]string, 0)
+	for _, suite := range repo.Config().Suites {
+		names = append(names, suite.Name)
+	}
+	return names
+}
+
+func repoSignCmd(flags *flag.FlagSet, asJSON bool) error {
+	if *repoOpts.key == "" {
+		flags.Usage()
+		return fmt.Errorf("key file is required")
+	}
+	repo, err := deb.OpenRepository(*repoOpts.root)
+	if err != nil {
+		return err
+	}
+	var passphrase []byte
+	if *repoOpts.passphraseFile != "" {
+		if passphrase, err = ioutil.ReadFile(*repoOpts.passphraseFile); err != nil {
+			return err
+		}
+		passphrase = []byte(strings.TrimRight(string(passphrase), "\r\n"))
+	}
+	key, err := os.Open(*repoOpts.key)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	if err := repo.Sign(key, passphrase, flags.Args()...); err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(map[string]interface{}{"signed": indexedSuites(repo, flags.Args())})
+	}
+	return nil
+}
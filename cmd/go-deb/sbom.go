@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	deb "github.com/isbm/go-deb"
+)
+
+// Options of the sbom command
+var sbomOpts struct {
+	format *string
+	name   *string
+	status *string
+	root   *string
+	distro *string
+}
+
+func sbomFlags(flags *flag.FlagSet) {
+	sbomOpts.format = flags.String("format", "spdx-json", "document format: spdx-json, spdx or cyclonedx")
+	sbomOpts.name = flags.String("name", "packages", "name of the described subject, e.g. an image")
+	sbomOpts.status = flags.String("status", "", "dpkg status database to include, e.g. "+deb.DPKG_STATUS_PATH)
+	sbomOpts.root = flags.String("root", "", "root directory of the system of the status database, for licenses and file checksums")
+	sbomOpts.distro = flags.String("distro", "debian", "distribution used in package URLs")
+}
+
+func sbomCmd(flags *flag.FlagSet, asJSON bool) error {
+	if flags.NArg() == 0 && *sbomOpts.status == "" {
+		flags.Usage()
+		return fmt.Errorf("no packages or status database given")
+	}
+	sbom := deb.NewSBOM(*sbomOpts.name).SetDistribution(*sbomOpts.distro)
+	for _, uri := range flags.Args() {
+		// SPDX requires SHA1 checksums of files
+		pf, err := deb.OpenPackageFile(uri, &deb.PackageOptions{Hash: deb.HASH_SHA256, Hashes: []int{deb.HASH_SHA1}, RecalculateChecksums: true})
+		if err != nil {
+			return fmt.Errorf("%s: %s", uri, err.Error())
+		}
+		sbom.AddPackageFile(pf)
+	}
+	if *sbomOpts.status != "" {
+		db, err := deb.OpenStatusDB(*sbomOpts.status)
+		if err != nil {
+			return err
+		}
+		sbom.AddStatusDB(db, *sbomOpts.root)
+	}
+
+	switch *sbomOpts.format {
+	case "spdx-json":
+		return sbom.WriteSPDXJSON(os.Stdout)
+	case "spdx":
+		return sbom.WriteSPDXTagValue(os.Stdout)
+	case "cyclonedx":
+		return sbom.WriteCycloneDXJSON(os.Stdout)
+	}
+	return fmt.Errorf("unknown format '%s'", *sbomOpts.format)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	deb "github.com/isbm/go-deb"
+)
+
+// Options of the verify command
+var verifyOpts struct {
+	keyring *string
+	suite   *string
+	index   *string
+	maxAge  *time.Duration
+}
+
+func verifyFlags(flags *flag.FlagSet) {
+	verifyOpts.keyring = flags.String("keyring", "", "trusted keys of the repository, to verify the package with its index")
+	verifyOpts.suite = flags.String("suite", "", "suite directory with InRelease or Release, e.g. dists/stable")
+	verifyOpts.index = flags.String("index", "", "Packages index relative to the suite, e.g. main/binary-amd64/Packages")
+	verifyOpts.maxAge = flags.Duration("max-age", 0, "maximal age of the Release file by its Date, e.g. 168h (Valid-Until is always checked)")
+}
+
+// Verify package against a signed index of a suite
+func verifyIndexed(pf *deb.PackageFile) (*deb.IndexEntry, error) {
+	if *verifyOpts.suite == "" || *verifyOpts.index == "" {
+		return nil, fmt.Errorf("suite and index are required with a keyring")
+	}
+	keyring, err := os.Open(*verifyOpts.keyring)
+	if err != nil {
+		return nil, err
+	}
+	defer keyring.Close()
+	vi, err := deb.OpenVerifiedIndex(keyring, *verifyOpts.suite, *verifyOpts.index, &deb.VerifyOptions{MaxAge: *verifyOpts.maxAge})
+	if err != nil {
+		return nil, err
+	}
+	return vi.VerifyPackage(pf)
+}
+
+func verifyCmd(flags *flag.FlagSet, asJSON bool) error {
+	if err := checkArgs(flags, 1, 1); err != nil {
+		return err
+	}
+	pf, err := deb.OpenPackageFile(flags.Arg(0), &deb.PackageOptions{Hash: deb.HASH_MD5, RecalculateChecksums: true})
+	if err != nil {
+		return err
+	}
+	report, err := pf.VerifyIntegrity()
+	if err != nil {
+		return err
+	}
+	var entry *deb.IndexEntry
+	if *verifyOpts.keyring != "" {
+		if entry, err = verifyIndexed(pf); err != nil {
+			return err
+		}
+	}
+
+	if asJSON {
+		result := map[string]interface{}{"integrity": report}
+		if entry != nil {
+			result["index"] = map[string]string{"filename": entry.Filename(), "sha256": entry.Field("SHA256")}
+		}
+		err = printJSON(result)
+	} else {
+		if entry != nil {
+			fmt.Printf("index: %s matches signed %s\n", entry.Filename(), *verifyOpts.index)
+		}
+		err = report.WriteText(os.Stdout)
+	}
+	if err == nil && !report.OK() {
+		err = fmt.Errorf("%s: integrity check failed", flags.Arg(0))
+	}
+	return err
+}
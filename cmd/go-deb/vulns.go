@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	deb "github.com/isbm/go-deb"
+)
+
+// Options of the vulns command
+var vulnsOpts struct {
+	release *string
+	tracker *string
+	oval    *string
+	dsa     *string
+	status  *string
+	index   *string
+}
+
+func vulnsFlags(flags *flag.FlagSet) {
+	vulnsOpts.release = flags.String("release", "", "codename of the release, e.g. bookworm")
+	vulnsOpts.tracker = flags.String("tracker", "", "security tracker JSON dump")
+	vulnsOpts.oval = flags.String("oval", "", "OVAL definitions of the release")
+	vulnsOpts.dsa = flags.String("dsa", "", "DSA list of the security tracker")
+	vulnsOpts.status = flags.String("status", "", "dpkg status database to check, e.g. "+deb.DPKG_STATUS_PATH)
+	vulnsOpts.index = flags.String("index", "", "Packages index or directory of packages to check")
+}
+
+// Load security data files into the database
+func loadSecurityDB() (*deb.SecurityDB, error) {
+	db := deb.NewSecurityDB(*vulnsOpts.release)
+	sources := []struct {
+		path string
+		load func(f *os.File) error
+	}{
+		{*vulnsOpts.tracker, func(f *os.File) error { return db.LoadTrackerJSON(f) }},
+		{*vulnsOpts.oval, func(f *os.File) error { return db.LoadOVAL(f) }},
+		{*vulnsOpts.dsa, func(f *os.File) error { return db.LoadDSAList(f) }},
+	}
+	warnings := 0
+	for _, src := range sources {
+		if src.path == "" {
+			continue
+		}
+		f, err := os.Open(src.path)
+		if err != nil {
+			return nil, err
+		}
+		err = src.load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", src.path, err.Error())
+		}
+		for _, warning := range db.Warnings()[warnings:] {
+			fmt.Fprintf(os.Stderr, "go-deb: %s: skipping %s\n", src.path, warning.Error())
+		}
+		warnings = len(db.Warnings())
+	}
+	return db, nil
+}
+
+func vulnsCmd(flags *flag.FlagSet, asJSON bool) error {
+	if *vulnsOpts.release == "" || (*vulnsOpts.tracker == "" && *vulnsOpts.oval == "" && *vulnsOpts.dsa == "") {
+		flags.Usage()
+		return fmt.Errorf("release and security data are required")
+	}
+	if flags.NArg() == 0 && *vulnsOpts.status == "" && *vulnsOpts.index == "" {
+		flags.Usage()
+		return fmt.Errorf("no packages, index or status database given")
+	}
+	db, err := loadSecurityDB()
+	if err != nil {
+		return err
+	}
+
+	report := &deb.VulnerabilityReport{Release: db.Release(), Vulnerabilities: make([]deb.Vulnerability, 0)}
+	for _, uri := range flags.Args() {
+		pf, err := openPackage(uri, true, deb.HASH_MD5)
+		if err != nil {
+			return fmt.Errorf("%s: %s", uri, err.Error())
+		}
+		report.Merge(db.MatchPackageFile(pf))
+	}
+	if *vulnsOpts.index != "" {
+		pi, err := deb.OpenPackagesIndex(*vulnsOpts.index)
+		if err != nil {
+			return err
+		}
+		report.Merge(db.MatchIndex(pi))
+	}
+	if *vulnsOpts.status != "" {
+		sdb, err := deb.OpenStatusDB(*vulnsOpts.status)
+		if err != nil {
+			return err
+		}
+		report.Merge(db.MatchStatusDB(sdb))
+	}
+
+	if asJSON {
+		return report.WriteJSON(os.Stdout)
+	}
+	return report.WriteText(os.Stdout)
+}
@@ -2,32 +2,158 @@ package deb
 
 import (
 	"bufio"
+	"fmt"
 	"strings"
 )
 
+// Conffile is an entry of the conffiles control file:
+//
+//	[flag ...] /absolute/path
+type Conffile struct {
+	path  string
+	flags []string
+	hash  string
+}
+
+// Path of the configuration file
+func (cf *Conffile) Path() string {
+	return cf.path
+}
+
+// Flags returns conffile flags, such as "remove-on-upgrade".
+func (cf *Conffile) Flags() []string {
+	return cf.flags
+}
+
+// HasFlag returns true if the conffile is marked with the flag.
+func (cf *Conffile) HasFlag(flag string) bool {
+	return in(flag, cf.flags)
+}
+
+// RemoveOnUpgrade returns true if the conffile is to be removed on upgrade.
+// Such conffiles are not shipped in the data archive.
+func (cf *Conffile) RemoveOnUpgrade() bool {
+	return cf.HasFlag("remove-on-upgrade")
+}
+
+// Hash returns MD5 checksum of the conffile shipped in the package, as dpkg
+// records it in its database. It is empty if files were not processed.
+func (cf *Conffile) Hash() string {
+	return cf.hash
+}
+
 type CfgFilesFile struct {
-	names []string
+	conffiles []Conffile
+	errors    []error
 }
 
 func NewCfgFilesFiles() *CfgFilesFile {
 	cfg := new(CfgFilesFile)
-	cfg.names = make([]string, 0)
+	cfg.conffiles = make([]Conffile, 0)
+	cfg.errors = make([]error, 0)
 	return cfg
 }
 
+// Parse conffiles. Invalid lines are skipped and collected as errors,
+// the first of them is returned.
 func (cfg *CfgFilesFile) parse(data []byte) error {
-	var line string
+	lineno := 0
 	scn := bufio.NewScanner(strings.NewReader(string(data)))
 	for scn.Scan() {
-		line = strings.TrimSpace(scn.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			cfg.names = append(cfg.names, line)
+		lineno++
+		line := strings.TrimSpace(scn.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		cf := Conffile{path: fields[len(fields)-1], flags: fields[:len(fields)-1]}
+		var err error
+		if !strings.HasPrefix(cf.path, "/") {
+			err = fmt.Errorf("conffile '%s' is not an absolute path", cf.path)
+		}
+		for _, flag := range cf.flags {
+			if flag != "remove-on-upgrade" {
+				err = fmt.Errorf("unknown flag '%s' for conffile '%s'", flag, cf.path)
+			}
 		}
+		if err != nil {
+			cfg.errors = append(cfg.errors, &ParseError{File: "conffiles", Line: lineno, Err: err.Error()})
+			continue
+		}
+		cfg.conffiles = append(cfg.conffiles, cf)
 	}
 
+	if len(cfg.errors) > 0 {
+		return cfg.errors[0]
+	}
 	return nil
 }
 
+// Names returns paths of the conffiles
 func (cfg *CfgFilesFile) Names() []string {
-	return cfg.names
+	names := make([]string, 0, len(cfg.conffiles))
+	for _, cf := range cfg.conffiles {
+		names = append(names, cf.path)
+	}
+	return names
+}
+
+// Conffiles returns all conffile entries
+func (cfg *CfgFilesFile) Conffiles() []Conffile {
+	return cfg.conffiles
+}
+
+// Get returns conffile by its absolute path, or nil.
+func (cfg *CfgFilesFile) Get(path string) *Conffile {
+	for idx := range cfg.conffiles {
+		if cfg.conffiles[idx].path == path {
+			return &cfg.conffiles[idx]
+		}
+	}
+	return nil
+}
+
+// Errors returns validation errors found while parsing.
+func (cfg *CfgFilesFile) Errors() []error {
+	return cfg.errors
+}
+
+// CheckConffiles cross-checks conffiles with the data archive: each of them
+// must be a regular file under /etc, and be shipped, unless it is to be
+// removed on upgrade. Returns all found problems as ConffileError. If the
+// data archive was not processed, only locations are checked and an error
+// says so.
+func (c *PackageFile) CheckConffiles() []error {
+	errs := make([]error, 0)
+	if len(c.conffiles.Conffiles()) == 0 {
+		return errs
+	}
+	processed := len(c.files) > 0
+	if !processed {
+		errs = append(errs, fmt.Errorf("data archive was not processed"))
+	}
+
+	files := make(map[string]FileInfo)
+	for _, fi := range c.files {
+		files[absPath(fi.Name())] = fi
+	}
+	for _, cf := range c.conffiles.Conffiles() {
+		if !strings.HasPrefix(cf.path, "/etc/") {
+			errs = append(errs, &ConffileError{Path: cf.path, Problem: CONFFILE_NOT_IN_ETC})
+		}
+		if !processed {
+			continue
+		}
+		fi, ok := files[cf.path]
+		switch {
+		case cf.RemoveOnUpgrade() && ok:
+			errs = append(errs, &ConffileError{Path: cf.path, Problem: CONFFILE_REMOVED_BUT_SHIPPED})
+		case cf.RemoveOnUpgrade():
+		case !ok:
+			errs = append(errs, &ConffileError{Path: cf.path, Problem: CONFFILE_NOT_SHIPPED})
+		case !fi.Mode().IsRegular():
+			errs = append(errs, &ConffileError{Path: cf.path, Problem: CONFFILE_NOT_REGULAR})
+		}
+	}
+	return errs
 }
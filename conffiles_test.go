@@ -0,0 +1,34 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+)
+
+func TestConffileHash(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBuilder().WriteDeb(&buf); err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum([]byte("greeting=hello\n"))
+	for _, rehash := range []bool{true, false} {
+		pf, err := NewPackageFileReader(bytes.NewReader(buf.Bytes())).SetMetaonly(false).
+			SetHash(HASH_SHA256).SetRecalculateChecksums(rehash).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf := pf.ConffilesFile().Get("/etc/hello.conf")
+		if cf == nil || cf.Hash() != hex.EncodeToString(sum[:]) {
+			t.Errorf("rehash %v: conffile %+v, expected MD5 %x", rehash, cf, sum)
+		}
+		sums := pf.GetCalculatedChecksums("./etc/hello.conf")
+		if _, ok := sums[HASH_MD5]; ok {
+			t.Errorf("rehash %v: MD5 calculated, but not requested", rehash)
+		}
+		if rehash && sums[HASH_SHA256] == "" {
+			t.Errorf("SHA256 of the conffile was not calculated")
+		}
+	}
+}
@@ -2,7 +2,6 @@ package deb
 
 import (
 	"errors"
-	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -38,10 +37,14 @@ type ControlFile struct {
 	description        string
 	summary            string // This is not a standard field of Dpkg and it basically contains only a first line of description.
 	originalMaintainer string
+
+	// All fields as they appear in the control file
+	fields *Paragraph
 }
 
 func NewControlFile() *ControlFile {
 	cf := new(ControlFile)
+	cf.fields = NewParagraph()
 	cf.depends = make([]string, 0)
 	cf.suggests = make([]string, 0)
 	cf.multiArch = ""
@@ -61,6 +64,9 @@ func in(a string, list []string) bool {
 
 // Add to the field
 func (cf *ControlFile) addToField(name string, data string) {
+	if name != "" {
+		cf.fields.appendLine(name, data[1:])
+	}
 	switch strings.ToLower(name) {
 	case "description":
 		cf.description += " " + strings.TrimSpace(data)
@@ -73,10 +79,14 @@ func (cf *ControlFile) setField(data ...string) error {
 		return errors.New("Data must have two elements only")
 	}
 	name, value := strings.ToLower(strings.TrimSpace(data[0])), strings.TrimSpace(data[1])
+	cf.fields.Set(strings.TrimSpace(data[0]), value)
 	i, err := strconv.Atoi(value)
-	if in(name, []string{"depends", "predepends", "suggests", "breaks", "enhances", "conflicts", "provides", "recommends", "replaces"}) {
+	// Relationship fields are named as in the control file, e.g. "Pre-Depends".
+	// Installed-Size is the only integer field, so numeric values of others,
+	// such as Version "1", stay strings.
+	if in(name, []string{"depends", "pre-depends", "suggests", "breaks", "enhances", "conflicts", "provides", "recommends", "replaces"}) {
 		cf.setFoldedField(name, value)
-	} else if err == nil {
+	} else if err == nil && name == "installed-size" {
 		cf.setIntField(name, i)
 	} else {
 		cf.setStringField(name, value)
@@ -91,7 +101,7 @@ func (cf *ControlFile) setFoldedField(name string, data string) {
 	switch name {
 	case "depends":
 		ptr = &cf.depends
-	case "predepends":
+	case "pre-depends":
 		ptr = &cf.predepends
 	case "suggests":
 		ptr = &cf.suggests
@@ -109,7 +119,6 @@ func (cf *ControlFile) setFoldedField(name string, data string) {
 		ptr = &cf.replaces
 	default:
 		ptr = nil
-		fmt.Println("@@ missing folded data for:", name)
 	}
 
 	// Try to make sense of that messy pile of many ways they call "standard"
@@ -171,11 +180,19 @@ func (cf *ControlFile) setStringField(name string, data string) {
 		cf.licence = data
 	case "oe":
 		cf.oe = data
-	default:
-		fmt.Println("Field", name, "is not yet supported:")
-		fmt.Println(data)
-		fmt.Println("---")
 	}
+	// Other fields are only kept as they are, see Field
+}
+
+// Field returns the value of any field by its case-insensitive name.
+// Multiline values have continuation lines joined with a newline.
+func (cf *ControlFile) Field(name string) string {
+	return cf.fields.Get(name)
+}
+
+// Fields returns names of all fields in order of appearance.
+func (cf *ControlFile) Fields() []string {
+	return cf.fields.Names()
 }
 
 // Source
@@ -0,0 +1,42 @@
+package deb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestControlFileFields(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewPackageBuilder().
+		SetField("Package", "numeric").
+		SetField("Version", "1").
+		SetField("Architecture", "all").
+		SetField("Pre-Depends", "dpkg (>= 1.19), init-system-helpers").
+		SetField("Installed-Size", "42").
+		SetField("X-Build-Id", "7").
+		WriteDeb(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pf, err := NewPackageFileReader(&buf).SetHash(HASH_MD5).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf := pf.ControlFile()
+	if cf.Version() != "1" {
+		t.Errorf("numeric version %q", cf.Version())
+	}
+	if cf.InstalledSize() != 42 {
+		t.Errorf("installed size %d", cf.InstalledSize())
+	}
+	if deps := cf.Predepends(); len(deps) != 2 || deps[0] != "dpkg (>= 1.19)" || deps[1] != "init-system-helpers" {
+		t.Errorf("pre-depends %q", deps)
+	}
+	if cf.Field("x-build-id") != "7" {
+		t.Errorf("unknown field %q", cf.Field("x-build-id"))
+	}
+	names := cf.Fields()
+	if len(names) != 6 || names[0] != "Package" || names[5] != "X-Build-Id" {
+		t.Errorf("fields %q", names)
+	}
+}
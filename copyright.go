@@ -0,0 +1,302 @@
+package deb
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CopyrightFiles is a "Files" paragraph of a machine-readable copyright file.
+type CopyrightFiles struct {
+	patterns  []string
+	copyright []string
+	license   string
+	text      string
+	comment   string
+	matcher   *regexp.Regexp
+}
+
+// Patterns returns the file patterns of the paragraph, relative to the source tree.
+func (cpf *CopyrightFiles) Patterns() []string {
+	return cpf.patterns
+}
+
+// Copyright returns the copyright statements, one per line.
+func (cpf *CopyrightFiles) Copyright() []string {
+	return cpf.copyright
+}
+
+// License returns the license short name or expression as it is written in the file.
+func (cpf *CopyrightFiles) License() string {
+	return cpf.license
+}
+
+// SPDX returns the license expression normalized to SPDX identifiers.
+func (cpf *CopyrightFiles) SPDX() string {
+	return NormalizeLicense(cpf.license)
+}
+
+// Text returns the license text, if it was given in place.
+func (cpf *CopyrightFiles) Text() string {
+	return cpf.text
+}
+
+// Comment returns the comment of the paragraph.
+func (cpf *CopyrightFiles) Comment() string {
+	return cpf.comment
+}
+
+// Matches returns true if a source path matches any of the paragraph patterns.
+func (cpf *CopyrightFiles) Matches(path string) bool {
+	return cpf.matcher != nil && cpf.matcher.MatchString(strings.TrimPrefix(path, "./"))
+}
+
+// Compile copyright patterns to a single regular expression, matching whole paths
+func copyrightMatcher(patterns []string) *regexp.Regexp {
+	var exprs []string
+	for _, pattern := range patterns {
+		exprs = append(exprs, copyrightPatternToRegexp(pattern))
+	}
+	return regexp.MustCompile("^(" + strings.Join(exprs, "|") + ")$")
+}
+
+// Convert a copyright pattern, where "*" matches anything including slashes
+// and "?" matches a single character, to a regular expression.
+func copyrightPatternToRegexp(pattern string) string {
+	var expr strings.Builder
+	pattern = strings.TrimPrefix(pattern, "./")
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+	return expr.String()
+}
+
+// CopyrightFile is /usr/share/doc/<package>/copyright. If it is written in the
+// machine-readable format (DEP-5), its paragraphs are parsed, otherwise only
+// the text is available.
+type CopyrightFile struct {
+	text            string
+	machineReadable bool
+	format          string
+	upstreamName    string
+	upstreamContact string
+	source          string
+	license         string
+	copyright       []string
+	files           []CopyrightFiles
+	licenses        map[string]string
+}
+
+// NewCopyrightFile constructor
+func NewCopyrightFile() *CopyrightFile {
+	cpr := new(CopyrightFile)
+	cpr.files = make([]CopyrightFiles, 0)
+	cpr.licenses = make(map[string]string)
+	cpr.copyright = make([]string, 0)
+	return cpr
+}
+
+// Parse copyright file
+func (cpr *CopyrightFile) parse(data []byte) error {
+	cpr.text = string(data)
+	for _, line := range strings.Split(cpr.text, "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := strings.ToLower(strings.SplitN(line, ":", 2)[0])
+		cpr.machineReadable = name == "format" || name == "format-specification"
+		break
+	}
+	if !cpr.machineReadable {
+		return nil
+	}
+
+	paragraphs, err := parseParagraphs(data)
+	if err != nil {
+		cpr.machineReadable = false
+		if perr, ok := err.(*ParseError); ok {
+			perr.File = "copyright"
+		}
+		return err
+	}
+
+	for idx, p := range paragraphs {
+		license, text := cpr.splitLicense(p.Get("License"))
+		switch {
+		case idx == 0:
+			cpr.format = p.Get("Format")
+			if cpr.format == "" {
+				cpr.format = p.Get("Format-Specification")
+			}
+			cpr.upstreamName = p.Get("Upstream-Name")
+			cpr.upstreamContact = p.Get("Upstream-Contact")
+			cpr.source = p.Get("Source")
+			cpr.license = license
+			cpr.copyright = cpr.splitLines(p.Get("Copyright"))
+		case p.Has("Files"):
+			cpf := CopyrightFiles{
+				patterns:  strings.Fields(p.Get("Files")),
+				copyright: cpr.splitLines(p.Get("Copyright")),
+				license:   license,
+				text:      text,
+				comment:   p.Get("Comment"),
+			}
+			cpf.matcher = copyrightMatcher(cpf.patterns)
+			cpr.files = append(cpr.files, cpf)
+		case p.Has("License"):
+			cpr.licenses[strings.ToLower(license)] = text
+		}
+	}
+
+	return nil
+}
+
+// Split License field into its short name on the first line and the text.
+func (cpr *CopyrightFile) splitLicense(value string) (string, string) {
+	nt := strings.SplitN(value, "\n", 2)
+	if len(nt) == 1 {
+		return strings.TrimSpace(nt[0]), ""
+	}
+	return strings.TrimSpace(nt[0]), nt[1]
+}
+
+// Split multiline field into non-empty trimmed lines
+func (cpr *CopyrightFile) splitLines(value string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != "." {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ParseCopyrightFile parses a copyright file, e.g. one installed in
+// /usr/share/doc. Malformed machine-readable files are kept as text.
+func ParseCopyrightFile(data []byte) *CopyrightFile {
+	cpr := NewCopyrightFile()
+	cpr.parse(data)
+	return cpr
+}
+
+// IsMachineReadable returns true if the copyright file is in DEP-5 format.
+func (cpr *CopyrightFile) IsMachineReadable() bool {
+	return cpr.machineReadable
+}
+
+// Text returns the copyright file as is.
+func (cpr *CopyrightFile) Text() string {
+	return cpr.text
+}
+
+// Format returns URI of the format specification.
+func (cpr *CopyrightFile) Format() string {
+	return cpr.format
+}
+
+// UpstreamName returns the name upstream uses for the software.
+func (cpr *CopyrightFile) UpstreamName() string {
+	return cpr.upstreamName
+}
+
+// UpstreamContact returns the preferred address to reach upstream.
+func (cpr *CopyrightFile) UpstreamContact() string {
+	return cpr.upstreamContact
+}
+
+// Source returns where the upstream source was obtained from.
+func (cpr *CopyrightFile) Source() string {
+	return cpr.source
+}
+
+// Files returns all "Files" paragraphs in order of appearance.
+func (cpr *CopyrightFile) Files() []CopyrightFiles {
+	return cpr.files
+}
+
+// LicenseText returns the text of a license, either from a standalone License
+// paragraph or from the Files paragraph where it was given in place.
+func (cpr *CopyrightFile) LicenseText(name string) string {
+	if text, ok := cpr.licenses[strings.ToLower(name)]; ok {
+		return text
+	}
+	for _, cpf := range cpr.files {
+		if strings.EqualFold(cpf.license, name) && cpf.text != "" {
+			return cpf.text
+		}
+	}
+	return ""
+}
+
+// FileLicense returns the SPDX license expression of a source file path.
+// As the format defines, the last matching Files paragraph wins.
+// An empty string is returned if no paragraph matches.
+func (cpr *CopyrightFile) FileLicense(path string) string {
+	for idx := len(cpr.files) - 1; idx >= 0; idx-- {
+		if cpr.files[idx].Matches(path) {
+			return cpr.files[idx].SPDX()
+		}
+	}
+	return ""
+}
+
+// LicenseMap returns SPDX license expressions per file pattern.
+func (cpr *CopyrightFile) LicenseMap() map[string]string {
+	lmap := make(map[string]string)
+	for _, cpf := range cpr.files {
+		for _, pattern := range cpf.patterns {
+			lmap[pattern] = cpf.SPDX()
+		}
+	}
+	return lmap
+}
+
+// Licenses returns sorted unique SPDX license expressions used in the file.
+func (cpr *CopyrightFile) Licenses() []string {
+	seen := make(map[string]bool)
+	licenses := make([]string, 0)
+	if cpr.license != "" {
+		seen[NormalizeLicense(cpr.license)] = true
+	}
+	for _, cpf := range cpr.files {
+		if cpf.license != "" {
+			seen[cpf.SPDX()] = true
+		}
+	}
+	for license := range seen {
+		licenses = append(licenses, license)
+	}
+	sort.Strings(licenses)
+	return licenses
+}
+
+// License returns an SPDX license expression covering the whole package,
+// combining all used licenses. If the header paragraph has its own License
+// field, it is used instead.
+func (cpr *CopyrightFile) License() string {
+	if cpr.license != "" {
+		return NormalizeLicense(cpr.license)
+	}
+	licenses := cpr.Licenses()
+	if len(licenses) > 1 {
+		for idx, license := range licenses {
+			if strings.Contains(license, " ") && !strings.HasPrefix(license, "(") {
+				licenses[idx] = "(" + license + ")"
+			}
+		}
+	}
+	return strings.Join(licenses, " AND ")
+}
@@ -0,0 +1,61 @@
+package deb
+
+import (
+	"sync"
+	"testing"
+)
+
+// Machine-readable copyright file with overriding Files paragraphs
+const testCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: hello
+
+Files: *
+Copyright: 2026 Jane Doe
+License: GPL-3+
+
+Files: src/lib/* ./doc/?.txt
+Copyright: 2026 John Doe
+License: Expat
+
+Files: src/lib/vendored\*.c
+Copyright: 2020 Someone Else
+License: BSD-3-clause
+`
+
+func TestCopyrightFileLicense(t *testing.T) {
+	cpr := ParseCopyrightFile([]byte(testCopyright))
+	tests := []struct {
+		path    string
+		license string
+	}{
+		{"README", "GPL-3.0-or-later"},
+		{"./src/main.c", "GPL-3.0-or-later"},
+		{"src/lib/util/strings.c", "MIT"},
+		{"doc/a.txt", "MIT"},
+		{"doc/ab.txt", "GPL-3.0-or-later"},
+		{"src/lib/vendored*.c", "BSD-3-Clause"},
+		{"src/lib/vendored.c", "MIT"},
+	}
+
+	// Paragraphs are matched concurrently and through copies
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, tt := range tests {
+				if license := cpr.FileLicense(tt.path); license != tt.license {
+					t.Errorf("%s: license %q, expected %q", tt.path, license, tt.license)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if files := cpr.Files(); len(files) != 3 || !files[1].Matches("src/lib/x.c") || files[2].Matches("src/lib/x.c") {
+		t.Errorf("copies of Files paragraphs match differently")
+	}
+	var empty CopyrightFiles
+	if empty.Matches("README") {
+		t.Errorf("paragraph without patterns matches")
+	}
+}
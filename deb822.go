@@ -0,0 +1,140 @@
+package deb
+
+import (
+	"bufio"
+	"strings"
+)
+
+// Paragraph is a single stanza of a deb822 formatted document, which is the
+// format of control, machine-readable copyright, debconf templates and
+// repository index files.
+//
+// Multiline values are kept with the leading space of each continuation line
+// removed and the lines joined with a newline.
+type Paragraph struct {
+	names  []string
+	fields map[string]string
+}
+
+// NewParagraph constructor
+func NewParagraph() *Paragraph {
+	p := new(Paragraph)
+	p.names = make([]string, 0)
+	p.fields = make(map[string]string)
+	return p
+}
+
+// Get returns the value of a field. Field names are case-insensitive.
+func (p *Paragraph) Get(name string) string {
+	return p.fields[strings.ToLower(name)]
+}
+
+// Has returns true if the field is present, even if it is empty.
+func (p *Paragraph) Has(name string) bool {
+	_, ok := p.fields[strings.ToLower(name)]
+	return ok
+}
+
+// Names returns field names in their original spelling and order of appearance.
+func (p *Paragraph) Names() []string {
+	return p.names
+}
+
+// Set a field value, keeping the position of an already existing field.
+func (p *Paragraph) Set(name string, value string) *Paragraph {
+	key := strings.ToLower(name)
+	if _, ok := p.fields[key]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.fields[key] = value
+	return p
+}
+
+// Delete removes a field.
+func (p *Paragraph) Delete(name string) *Paragraph {
+	key := strings.ToLower(name)
+	if _, ok := p.fields[key]; !ok {
+		return p
+	}
+	delete(p.fields, key)
+	for i, n := range p.names {
+		if strings.ToLower(n) == key {
+			p.names = append(p.names[:i], p.names[i+1:]...)
+			break
+		}
+	}
+	return p
+}
+
+// Append a continuation line to the field
+func (p *Paragraph) appendLine(name string, line string) {
+	key := strings.ToLower(name)
+	if p.fields[key] == "" {
+		p.fields[key] = line
+	} else {
+		p.fields[key] += "\n" + line
+	}
+}
+
+// String returns the paragraph in deb822 format, without the trailing empty line.
+func (p *Paragraph) String() string {
+	var buf strings.Builder
+	for _, name := range p.names {
+		lines := strings.Split(p.fields[strings.ToLower(name)], "\n")
+		buf.WriteString(name + ":")
+		if lines[0] != "" {
+			buf.WriteString(" " + lines[0])
+		}
+		buf.WriteString("\n")
+		for _, line := range lines[1:] {
+			if strings.TrimSpace(line) == "" {
+				line = "."
+			}
+			buf.WriteString(" " + line + "\n")
+		}
+	}
+	return buf.String()
+}
+
+// Parse deb822 data into paragraphs. Comment lines are skipped.
+func parseParagraphs(data []byte) ([]*Paragraph, error) {
+	var current *Paragraph
+	var name string
+	paragraphs := make([]*Paragraph, 0)
+
+	scn := bufio.NewScanner(strings.NewReader(string(data)))
+	scn.Buffer(make([]byte, 0, 0x10000), 0x1000000)
+	lineno := 0
+	for scn.Scan() {
+		lineno++
+		line := strings.TrimRight(scn.Text(), " \t\r")
+		if strings.TrimSpace(line) == "" {
+			current = nil
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if current == nil {
+				return nil, &ParseError{Line: lineno, Err: "continuation line outside of a field"}
+			}
+			current.appendLine(name, line[1:])
+			continue
+		}
+
+		nv := strings.SplitN(line, ":", 2)
+		if len(nv) != 2 || strings.TrimSpace(nv[0]) == "" {
+			return nil, &ParseError{Line: lineno, Err: "expected a 'Field: value' line"}
+		}
+		if current == nil {
+			current = NewParagraph()
+			paragraphs = append(paragraphs, current)
+		}
+		name = strings.TrimSpace(nv[0])
+		current.Set(name, strings.TrimSpace(nv[1]))
+	}
+
+	return paragraphs, scn.Err()
+}
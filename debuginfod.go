@@ -0,0 +1,137 @@
+package deb
+
+import (
+	"archive/tar"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Directory of sources shipped with debug symbols, e.g. by debugedit
+const DEBUG_SOURCE_DIR = "/usr/src/debug"
+
+// DebuginfodServer is an http.Handler implementing the debuginfod API out
+// of local packages of a build ID index:
+//
+//	/buildid/<id>/debuginfo
+//	/buildid/<id>/executable
+//	/buildid/<id>/source/<absolute path>
+//
+// Sources are looked up in packages of the build ID, at the path or under
+// DEBUG_SOURCE_DIR. Files are streamed out of packages, and their resolved
+// locations are cached, so links are followed and sources are searched
+// only once.
+type DebuginfodServer struct {
+	index    *BuildIdIndex
+	resolved map[string]debuginfodFile
+	mu       sync.RWMutex
+}
+
+// File of a package served for a request
+type debuginfodFile struct {
+	entry *BuildIdEntry
+	name  string
+}
+
+// NewDebuginfodServer constructor
+func NewDebuginfodServer(index *BuildIdIndex) *DebuginfodServer {
+	ds := new(DebuginfodServer)
+	ds.index = index
+	ds.resolved = make(map[string]debuginfodFile)
+	return ds
+}
+
+// Index returns the build ID index of the server.
+func (ds *DebuginfodServer) Index() *BuildIdIndex {
+	return ds.index
+}
+
+// ServeHTTP serves GET and HEAD requests of the debuginfod API.
+func (ds *DebuginfodServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 4)
+	if len(parts) < 3 || parts[0] != "buildid" {
+		http.NotFound(w, r)
+		return
+	}
+	id := strings.ToLower(parts[1])
+	if _, err := BuildIdDebugPath(id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var candidates []debuginfodFile
+	key := id + "/" + parts[2]
+	switch {
+	case parts[2] == BUILD_ID_DEBUGINFO && len(parts) == 3, parts[2] == BUILD_ID_EXECUTABLE && len(parts) == 3:
+		if entry := ds.index.Find(id, parts[2]); entry != nil {
+			candidates = append(candidates, debuginfodFile{entry: entry, name: entry.Path})
+		}
+	case parts[2] == "source" && len(parts) == 4:
+		name := path.Clean("/" + parts[3])
+		key += name
+		candidates = ds.sources(id, name)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	ds.mu.RLock()
+	if file, ok := ds.resolved[key]; ok {
+		candidates = []debuginfodFile{file}
+	}
+	ds.mu.RUnlock()
+
+	written := false
+	for _, file := range candidates {
+		err := StreamPackageFile(file.entry.PackagePath, file.name, func(hdr *tar.Header, content io.Reader) error {
+			ds.mu.Lock()
+			ds.resolved[key] = debuginfodFile{entry: file.entry, name: absPath(hdr.Name)}
+			ds.mu.Unlock()
+
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", strconv.FormatInt(hdr.Size, 10))
+			w.Header().Set("X-Debuginfod-Size", strconv.FormatInt(hdr.Size, 10))
+			w.Header().Set("X-Debuginfod-Archive", file.entry.PackagePath)
+			w.Header().Set("X-Debuginfod-File", absPath(hdr.Name))
+			w.WriteHeader(http.StatusOK)
+			written = true
+			if r.Method == http.MethodHead {
+				return nil
+			}
+			_, err := io.Copy(w, content)
+			return err
+		})
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil && !written {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// Candidate source files of a build ID in its packages, debug symbols first
+func (ds *DebuginfodServer) sources(id string, name string) []debuginfodFile {
+	files := make([]debuginfodFile, 0)
+	searched := make(map[string]bool)
+	for _, kind := range []string{BUILD_ID_DEBUGINFO, BUILD_ID_EXECUTABLE} {
+		for _, entry := range ds.index.Lookup(id) {
+			if entry.Kind != kind || searched[entry.PackagePath] {
+				continue
+			}
+			searched[entry.PackagePath] = true
+			files = append(files, debuginfodFile{entry: entry, name: name}, debuginfodFile{entry: entry, name: path.Join(DEBUG_SOURCE_DIR, name)})
+		}
+	}
+	return files
+}
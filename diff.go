@@ -0,0 +1,464 @@
+package deb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Control fields holding package relations
+var relationFields = []string{"Pre-Depends", "Depends", "Recommends", "Suggests", "Enhances", "Breaks", "Conflicts", "Provides", "Replaces", "Built-Using"}
+
+// FieldChange is a changed control field. Old or New is empty if the field
+// was added or removed.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// RelationChange lists relations added to and removed from a relationship field.
+type RelationChange struct {
+	Field   string   `json:"field"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// FileChange is a change of a file in the data archive. Kind is one of
+// "added", "removed", "modified", "type", "link", "mode" or "owner".
+type FileChange struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// ScriptChange is a unified diff of a maintainer script.
+type ScriptChange struct {
+	Script string `json:"script"`
+	Diff   string `json:"diff"`
+}
+
+// ConffileChange is a change of a conffile. Kind is one of "added",
+// "removed", "modified" or "flags".
+type ConffileChange struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// PackageDiff describes differences between two packages.
+type PackageDiff struct {
+	Package    string           `json:"package"`
+	OldVersion string           `json:"old_version"`
+	NewVersion string           `json:"new_version"`
+	Fields     []FieldChange    `json:"fields"`
+	Relations  []RelationChange `json:"relations"`
+	Files      []FileChange     `json:"files"`
+	Scripts    []ScriptChange   `json:"scripts"`
+	Conffiles  []ConffileChange `json:"conffiles"`
+}
+
+// Diff compares two packages, usually two versions of the same package:
+// control fields, relations, files in the data archive, maintainer scripts
+// and conffiles. File contents are compared by calculated checksums, so
+// both packages should be read with files processed and the same hash type.
+func Diff(a *PackageFile, b *PackageFile) *PackageDiff {
+	d := &PackageDiff{
+		Package:    b.ControlFile().Package(),
+		OldVersion: a.ControlFile().Version(),
+		NewVersion: b.ControlFile().Version(),
+		Fields:     make([]FieldChange, 0),
+		Relations:  make([]RelationChange, 0),
+		Files:      make([]FileChange, 0),
+		Scripts:    make([]ScriptChange, 0),
+		Conffiles:  make([]ConffileChange, 0),
+	}
+	d.diffFields(a.ControlFile(), b.ControlFile())
+	d.diffFiles(a, b)
+	d.diffScripts(a, b)
+	d.diffConffiles(a.ConffilesFile(), b.ConffilesFile())
+	return d
+}
+
+// Compare control fields, relationship fields separately
+func (d *PackageDiff) diffFields(a *ControlFile, b *ControlFile) {
+	names := append([]string{}, a.Fields()...)
+	for _, name := range b.Fields() {
+		if a.fields.Has(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	for _, name := range names {
+		isRelation := false
+		for _, rf := range relationFields {
+			isRelation = isRelation || strings.EqualFold(rf, name)
+		}
+		if isRelation {
+			continue
+		}
+		if old, new := a.Field(name), b.Field(name); old != new || a.fields.Has(name) != b.fields.Has(name) {
+			d.Fields = append(d.Fields, FieldChange{Field: name, Old: old, New: new})
+		}
+	}
+
+	d.Relations = relationChanges(a, b)
+}
+
+// Source of control field values, such as a control file or an index entry
+type fieldSource interface {
+	Field(name string) string
+}
+
+// Compare relationship fields of two stanzas
+func relationChanges(a fieldSource, b fieldSource) []RelationChange {
+	changes := make([]RelationChange, 0)
+	for _, name := range relationFields {
+		old, new := relationStrings(a.Field(name)), relationStrings(b.Field(name))
+		rc := RelationChange{Field: name, Added: make([]string, 0), Removed: make([]string, 0)}
+		for _, rel := range new {
+			if !in(rel, old) {
+				rc.Added = append(rc.Added, rel)
+			}
+		}
+		for _, rel := range old {
+			if !in(rel, new) {
+				rc.Removed = append(rc.Removed, rel)
+			}
+		}
+		if len(rc.Added) > 0 || len(rc.Removed) > 0 {
+			changes = append(changes, rc)
+		}
+	}
+	return changes
+}
+
+// Normalized relation groups of a field value
+func relationStrings(value string) []string {
+	rels := make([]string, 0)
+	groups, err := ParseRelations(value)
+	if err != nil {
+		for _, rel := range strings.Split(value, ",") {
+			if rel = strings.Join(strings.Fields(rel), " "); rel != "" {
+				rels = append(rels, rel)
+			}
+		}
+		return rels
+	}
+	for idx := range groups {
+		rels = append(rels, groups[idx].String())
+	}
+	return rels
+}
+
+// Type of a file in a package as a word
+func fileType(fi *FileInfo) string {
+	switch {
+	case fi.Mode().IsDir():
+		return "directory"
+	case fi.Mode()&os.ModeSymlink != 0:
+		return "symlink"
+	case fi.Mode().IsRegular():
+		return "file"
+	}
+	return "special"
+}
+
+// Compare files of data archives
+func (d *PackageDiff) diffFiles(a *PackageFile, b *PackageFile) {
+	old := make(map[string]FileInfo)
+	for _, fi := range a.Files() {
+		old[absPath(fi.Name())] = fi
+	}
+	seen := make(map[string]bool)
+	for _, nfi := range b.Files() {
+		nfi := nfi
+		path := absPath(nfi.Name())
+		seen[path] = true
+		ofi, ok := old[path]
+		if !ok {
+			d.Files = append(d.Files, FileChange{Path: path, Kind: "added"})
+			continue
+		}
+
+		if fileType(&ofi) != fileType(&nfi) {
+			d.Files = append(d.Files, FileChange{Path: path, Kind: "type", Old: fileType(&ofi), New: fileType(&nfi)})
+			continue
+		}
+		if ofi.Linkname() != nfi.Linkname() {
+			d.Files = append(d.Files, FileChange{Path: path, Kind: "link", Old: ofi.Linkname(), New: nfi.Linkname()})
+		}
+		if nfi.Mode().IsRegular() {
+			osum, nsum := a.GetCalculatedChecksum(ofi.Name()), b.GetCalculatedChecksum(nfi.Name())
+			if ofi.Size() != nfi.Size() || osum != nsum {
+				d.Files = append(d.Files, FileChange{Path: path, Kind: "modified", Old: osum, New: nsum})
+			}
+		}
+		if ofi.Perm() != nfi.Perm() && nfi.Mode()&os.ModeSymlink == 0 {
+			d.Files = append(d.Files, FileChange{Path: path, Kind: "mode", Old: fmt.Sprintf("%04o", ofi.Perm()), New: fmt.Sprintf("%04o", nfi.Perm())})
+		}
+		if ofi.Owner() != nfi.Owner() || ofi.Group() != nfi.Group() {
+			d.Files = append(d.Files, FileChange{Path: path, Kind: "owner", Old: ofi.Owner() + "/" + ofi.Group(), New: nfi.Owner() + "/" + nfi.Group()})
+		}
+	}
+	for _, ofi := range a.Files() {
+		if path := absPath(ofi.Name()); !seen[path] {
+			d.Files = append(d.Files, FileChange{Path: path, Kind: "removed"})
+		}
+	}
+	sort.SliceStable(d.Files, func(i, j int) bool { return d.Files[i].Path < d.Files[j].Path })
+}
+
+// Compare maintainer scripts
+func (d *PackageDiff) diffScripts(a *PackageFile, b *PackageFile) {
+	old := []string{a.PreInstallScript(), a.PostInstallScript(), a.PreUninstallScript(), a.PostUninstallScript(), a.ConfigScript()}
+	new := []string{b.PreInstallScript(), b.PostInstallScript(), b.PreUninstallScript(), b.PostUninstallScript(), b.ConfigScript()}
+	for idx, name := range []string{"preinst", "postinst", "prerm", "postrm", "config"} {
+		if old[idx] != new[idx] {
+			d.Scripts = append(d.Scripts, ScriptChange{Script: name, Diff: UnifiedDiff("a/"+name, "b/"+name, old[idx], new[idx])})
+		}
+	}
+}
+
+// Compare conffiles
+func (d *PackageDiff) diffConffiles(a *CfgFilesFile, b *CfgFilesFile) {
+	for _, ncf := range b.Conffiles() {
+		ocf := a.Get(ncf.Path())
+		switch {
+		case ocf == nil:
+			d.Conffiles = append(d.Conffiles, ConffileChange{Path: ncf.Path(), Kind: "added"})
+			continue
+		case ocf.Hash() != ncf.Hash():
+			d.Conffiles = append(d.Conffiles, ConffileChange{Path: ncf.Path(), Kind: "modified", Old: ocf.Hash(), New: ncf.Hash()})
+		}
+		if oflags, nflags := strings.Join(ocf.Flags(), " "), strings.Join(ncf.Flags(), " "); oflags != nflags {
+			d.Conffiles = append(d.Conffiles, ConffileChange{Path: ncf.Path(), Kind: "flags", Old: oflags, New: nflags})
+		}
+	}
+	for _, ocf := range a.Conffiles() {
+		if b.Get(ocf.Path()) == nil {
+			d.Conffiles = append(d.Conffiles, ConffileChange{Path: ocf.Path(), Kind: "removed"})
+		}
+	}
+}
+
+// Empty returns true if packages do not differ.
+func (d *PackageDiff) Empty() bool {
+	return d.OldVersion == d.NewVersion && len(d.Fields) == 0 && len(d.Relations) == 0 &&
+		len(d.Files) == 0 && len(d.Scripts) == 0 && len(d.Conffiles) == 0
+}
+
+// WriteJSON writes the diff as a JSON object.
+func (d *PackageDiff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteText writes a human-readable report of the diff.
+func (d *PackageDiff) WriteText(w io.Writer) error {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("Package: %s %s -> %s\n", d.Package, d.OldVersion, d.NewVersion))
+	if len(d.Fields) > 0 {
+		out.WriteString("\nControl fields:\n")
+		for _, fc := range d.Fields {
+			switch {
+			case fc.Old == "":
+				out.WriteString(fmt.Sprintf("  + %s: %s\n", fc.Field, oneLine(fc.New)))
+			case fc.New == "":
+				out.WriteString(fmt.Sprintf("  - %s: %s\n", fc.Field, oneLine(fc.Old)))
+			default:
+				out.WriteString(fmt.Sprintf("  ~ %s: %s -> %s\n", fc.Field, oneLine(fc.Old), oneLine(fc.New)))
+			}
+		}
+	}
+	if len(d.Relations) > 0 {
+		out.WriteString("\nRelations:\n")
+		for _, rc := range d.Relations {
+			for _, rel := range rc.Added {
+				out.WriteString(fmt.Sprintf("  + %s: %s\n", rc.Field, rel))
+			}
+			for _, rel := range rc.Removed {
+				out.WriteString(fmt.Sprintf("  - %s: %s\n", rc.Field, rel))
+			}
+		}
+	}
+	if len(d.Files) > 0 {
+		out.WriteString("\nFiles:\n")
+		for _, fc := range d.Files {
+			switch fc.Kind {
+			case "added":
+				out.WriteString(fmt.Sprintf("  + %s\n", fc.Path))
+			case "removed":
+				out.WriteString(fmt.Sprintf("  - %s\n", fc.Path))
+			case "modified":
+				out.WriteString(fmt.Sprintf("  ~ %s\n", fc.Path))
+			default:
+				out.WriteString(fmt.Sprintf("  ~ %s: %s %s -> %s\n", fc.Path, fc.Kind, fc.Old, fc.New))
+			}
+		}
+	}
+	if len(d.Conffiles) > 0 {
+		out.WriteString("\nConffiles:\n")
+		for _, cc := range d.Conffiles {
+			out.WriteString(strings.TrimRight(fmt.Sprintf("  %s %s %s %s", cc.Kind, cc.Path, cc.Old, cc.New), " ") + "\n")
+		}
+	}
+	for _, sc := range d.Scripts {
+		out.WriteString("\n" + sc.Diff)
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// Shorten multiline value for one-line display
+func oneLine(value string) string {
+	if idx := strings.Index(value, "\n"); idx > -1 {
+		return value[:idx] + " ..."
+	}
+	return value
+}
+
+// Maximum size of the LCS table of UnifiedDiff; larger inputs are
+// reported as a single hunk replacing the whole text
+const diffMaxCells = 4 << 20
+
+// UnifiedDiff returns a unified diff of two texts with three lines of context,
+// or an empty string if they are equal.
+func UnifiedDiff(oldName string, newName string, old string, new string) string {
+	if old == new {
+		return ""
+	}
+	a, b := diffLines(old), diffLines(new)
+
+	// Edit script: ' ' keeps, '-' removes a line of a, '+' adds a line of b
+	ops := make([]byte, 0, len(a)+len(b))
+	if (len(a)+1)*(len(b)+1) > diffMaxCells {
+		for range a {
+			ops = append(ops, '-')
+		}
+		for range b {
+			ops = append(ops, '+')
+		}
+	} else {
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				switch {
+				case a[i] == b[j]:
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				case lcs[i+1][j] >= lcs[i][j+1]:
+					lcs[i][j] = lcs[i+1][j]
+				default:
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(a) || j < len(b) {
+			switch {
+			case i < len(a) && j < len(b) && a[i] == b[j]:
+				ops = append(ops, ' ')
+				i++
+				j++
+			case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, '-')
+				i++
+			default:
+				ops = append(ops, '+')
+				j++
+			}
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+	const context = 3
+	for start := 0; start < len(ops); {
+		if ops[start] == ' ' {
+			start++
+			continue
+		}
+		// Extend the hunk while changes are closer than twice the context
+		end := start
+		for end < len(ops) {
+			next := end
+			for next < len(ops) && ops[next] == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*context {
+				break
+			}
+			for next < len(ops) && ops[next] != ' ' {
+				next++
+			}
+			end = next
+		}
+		first, last := start-context, end+context
+		if first < 0 {
+			first = 0
+		}
+		if last > len(ops) {
+			last = len(ops)
+		}
+
+		// Line positions at the hunk start
+		ai, bi := 0, 0
+		for _, op := range ops[:first] {
+			if op != '+' {
+				ai++
+			}
+			if op != '-' {
+				bi++
+			}
+		}
+		var body strings.Builder
+		acount, bcount := 0, 0
+		for _, op := range ops[first:last] {
+			switch op {
+			case ' ':
+				body.WriteString(" " + a[ai+acount] + "\n")
+				acount++
+				bcount++
+			case '-':
+				body.WriteString("-" + a[ai+acount] + "\n")
+				acount++
+			case '+':
+				body.WriteString("+" + b[bi+bcount] + "\n")
+				bcount++
+			}
+		}
+		out.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(ai, acount), hunkRange(bi, bcount)))
+		out.WriteString(body.String())
+		start = last
+	}
+	return out.String()
+}
+
+// Split text to lines for diffing
+func diffLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Line range of a hunk in the unified diff format
+func hunkRange(start int, count int) string {
+	switch {
+	case count == 0:
+		return fmt.Sprintf("%d,0", start)
+	case count == 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
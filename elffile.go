@@ -0,0 +1,169 @@
+package deb
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+	"io/ioutil"
+)
+
+// Type of the ELF note with the GNU build ID
+const NT_GNU_BUILD_ID = 3
+
+// ElfSymbol is an undefined dynamic symbol, imported by an ELF object.
+type ElfSymbol struct {
+	name    string
+	version string
+	library string
+}
+
+// Name of the symbol
+func (es *ElfSymbol) Name() string {
+	return es.name
+}
+
+// Version of the symbol, e.g. GLIBC_2.2.5. It is empty for unversioned symbols.
+func (es *ElfSymbol) Version() string {
+	return es.version
+}
+
+// Library returns SONAME of the library the symbol version is required from.
+// It is empty for unversioned symbols.
+func (es *ElfSymbol) Library() string {
+	return es.library
+}
+
+// ElfFile is a summary of dynamic linking information of an ELF object
+// found in the data archive of a package.
+type ElfFile struct {
+	path    string
+	soname  string
+	machine string
+	class   string
+	needed  []string
+	symbols []ElfSymbol
+	buildID string
+	debug   bool
+}
+
+// Read ELF object information from its content. Returns nil if data is not an ELF object.
+func newElfFile(path string, data []byte) *ElfFile {
+	if !bytes.HasPrefix(data, []byte(elf.ELFMAG)) {
+		return nil
+	}
+	obj, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	defer obj.Close()
+
+	ef := new(ElfFile)
+	ef.path = path
+	ef.machine = obj.Machine.String()
+	ef.class = obj.Class.String()
+	ef.needed = make([]string, 0)
+	ef.symbols = make([]ElfSymbol, 0)
+
+	// Static objects have no dynamic section, which is not an error
+	if needed, err := obj.ImportedLibraries(); err == nil {
+		ef.needed = append(ef.needed, needed...)
+	}
+	if soname, err := obj.DynString(elf.DT_SONAME); err == nil && len(soname) > 0 {
+		ef.soname = soname[0]
+	}
+	if symbols, err := obj.ImportedSymbols(); err == nil {
+		for _, sym := range symbols {
+			ef.symbols = append(ef.symbols, ElfSymbol{name: sym.Name, version: sym.Version, library: sym.Library})
+		}
+	}
+
+	ef.buildID = elfBuildID(obj)
+	for _, name := range []string{".debug_info", ".zdebug_info"} {
+		if sec := obj.Section(name); sec != nil && sec.Type != elf.SHT_NOBITS {
+			ef.debug = true
+		}
+	}
+
+	return ef
+}
+
+// GNU build ID of an ELF object from its note sections, or from note
+// segments if it has no section headers. Empty if it has no build ID.
+func elfBuildID(obj *elf.File) string {
+	notes := make([][]byte, 0)
+	for _, sec := range obj.Sections {
+		if sec.Type == elf.SHT_NOTE {
+			if data, err := sec.Data(); err == nil {
+				notes = append(notes, data)
+			}
+		}
+	}
+	if len(notes) == 0 {
+		for _, prog := range obj.Progs {
+			if prog.Type == elf.PT_NOTE {
+				if data, err := ioutil.ReadAll(prog.Open()); err == nil {
+					notes = append(notes, data)
+				}
+			}
+		}
+	}
+
+	align := func(n uint32) uint64 { return (uint64(n) + 3) &^ 3 }
+	for _, data := range notes {
+		for len(data) >= 12 {
+			namesz, descsz, ntype := obj.ByteOrder.Uint32(data), obj.ByteOrder.Uint32(data[4:]), obj.ByteOrder.Uint32(data[8:])
+			data = data[12:]
+			if align(namesz)+align(descsz) > uint64(len(data)) {
+				break
+			}
+			name, desc := data[:namesz], data[align(namesz):align(namesz)+uint64(descsz)]
+			if ntype == NT_GNU_BUILD_ID && string(bytes.TrimRight(name, "\x00")) == "GNU" {
+				return hex.EncodeToString(desc)
+			}
+			data = data[align(namesz)+align(descsz):]
+		}
+	}
+	return ""
+}
+
+// Path of the ELF object in the package
+func (ef *ElfFile) Path() string {
+	return ef.path
+}
+
+// Soname returns DT_SONAME of a shared library. It is empty for executables.
+func (ef *ElfFile) Soname() string {
+	return ef.soname
+}
+
+// Machine returns the ELF machine, e.g. EM_X86_64
+func (ef *ElfFile) Machine() string {
+	return ef.machine
+}
+
+// Class returns the ELF class, e.g. ELFCLASS64
+func (ef *ElfFile) Class() string {
+	return ef.class
+}
+
+// Needed returns DT_NEEDED entries, which are SONAMEs of required libraries.
+func (ef *ElfFile) Needed() []string {
+	return ef.needed
+}
+
+// Symbols returns undefined dynamic symbols of the object.
+func (ef *ElfFile) Symbols() []ElfSymbol {
+	return ef.symbols
+}
+
+// BuildID returns the GNU build ID of the object, lowercase hex. It is
+// empty if the object was linked without one.
+func (ef *ElfFile) BuildID() string {
+	return ef.buildID
+}
+
+// HasDebugInfo returns true if the object has DWARF debugging information,
+// i.e. it is unstripped or it is a file of detached debug symbols.
+func (ef *ElfFile) HasDebugInfo() bool {
+	return ef.debug
+}
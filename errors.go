@@ -0,0 +1,46 @@
+package deb
+
+import "fmt"
+
+// ParseError describes a syntax error at a particular line of a package
+// meta-data file, such as copyright, symbols or shlibs.
+type ParseError struct {
+	File string
+	Line int
+	Err  string
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+}
+
+// Problems of conffiles, named as lint tags
+const (
+	CONFFILE_NOT_IN_ETC          = "conffile-not-in-etc"
+	CONFFILE_NOT_SHIPPED         = "conffile-not-shipped"
+	CONFFILE_REMOVED_BUT_SHIPPED = "conffile-remove-on-upgrade-but-shipped"
+	CONFFILE_NOT_REGULAR         = "conffile-not-regular-file"
+)
+
+// ConffileError describes a problem of a conffile, found by CheckConffiles.
+type ConffileError struct {
+	Path    string
+	Problem string
+}
+
+func (e *ConffileError) Error() string {
+	switch e.Problem {
+	case CONFFILE_NOT_IN_ETC:
+		return fmt.Sprintf("conffile '%s' is not under /etc", e.Path)
+	case CONFFILE_NOT_SHIPPED:
+		return fmt.Sprintf("conffile '%s' is not shipped in the data archive", e.Path)
+	case CONFFILE_REMOVED_BUT_SHIPPED:
+		return fmt.Sprintf("conffile '%s' is marked remove-on-upgrade, but shipped", e.Path)
+	case CONFFILE_NOT_REGULAR:
+		return fmt.Sprintf("conffile '%s' is not a regular file", e.Path)
+	}
+	return fmt.Sprintf("conffile '%s': %s", e.Path, e.Problem)
+}
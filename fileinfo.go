@@ -43,6 +43,22 @@ func (f *FileInfo) Mode() os.FileMode {
 	return f.mode
 }
 
+// Perm returns permission bits of a file in a Deb package, including setuid,
+// setgid and sticky bits, as a Unix mode number, e.g. 04755
+func (f *FileInfo) Perm() uint32 {
+	perm := uint32(f.mode.Perm())
+	if f.mode&os.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if f.mode&os.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if f.mode&os.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return perm
+}
+
 // ModTime is the modification time of a file in a Deb package
 func (f *FileInfo) ModTime() time.Time {
 	return f.modTime
@@ -1,9 +1,16 @@
 module github.com/isbm/go-deb
 
-go 1.13
+go 1.23.0
 
 require (
+	github.com/ProtonMail/go-crypto v1.5.2
 	github.com/andrew-d/lzma v0.0.0-20120628231508-2a7c55cad4a2
 	github.com/blakesmith/ar v0.0.0-20190502131153-809d4375e1fb
 	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8
+	golang.org/x/crypto v0.41.0
+)
+
+require (
+	github.com/cloudflare/circl v1.6.3 // indirect
+	golang.org/x/sys v0.35.0 // indirect
 )
@@ -0,0 +1,73 @@
+package deb
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// New hash of a hash type
+func newHash(hashType int) hash.Hash {
+	switch hashType {
+	case HASH_MD5:
+		return md5.New()
+	case HASH_SHA1:
+		return sha1.New()
+	case HASH_SHA256:
+		return sha256.New()
+	case HASH_SHA512:
+		return sha512.New()
+	case HASH_BLAKE2B:
+		h, _ := blake2b.New512(nil) // fails only with a key too long
+		return h
+	}
+	panic(fmt.Sprintf("Unknown hash: %d", hashType))
+}
+
+// Hashes of several types, computed in a single pass
+type multiHash struct {
+	hashes map[int]hash.Hash
+	writer io.Writer
+}
+
+// Constructor. Duplicate hash types are computed once.
+func newMultiHash(hashTypes ...int) *multiHash {
+	mh := new(multiHash)
+	mh.hashes = make(map[int]hash.Hash)
+	writers := make([]io.Writer, 0)
+	for _, ht := range hashTypes {
+		if _, ok := mh.hashes[ht]; !ok {
+			mh.hashes[ht] = newHash(ht)
+			writers = append(writers, mh.hashes[ht])
+		}
+	}
+	mh.writer = io.MultiWriter(writers...)
+	return mh
+}
+
+func (mh *multiHash) Write(p []byte) (int, error) {
+	return mh.writer.Write(p)
+}
+
+// Reset all hashes to compute checksums of other data
+func (mh *multiHash) Reset() {
+	for _, h := range mh.hashes {
+		h.Reset()
+	}
+}
+
+// Checksums encoded in hexadecimal, by hash type
+func (mh *multiHash) Sums() map[int]string {
+	sums := make(map[int]string)
+	for ht, h := range mh.hashes {
+		sums[ht] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums
+}
@@ -0,0 +1,310 @@
+package deb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xi2/xz"
+)
+
+// IndexEntry is a binary package stanza of a repository Packages index.
+type IndexEntry struct {
+	fields *Paragraph
+}
+
+// NewIndexEntry constructor
+func NewIndexEntry() *IndexEntry {
+	ie := new(IndexEntry)
+	ie.fields = NewParagraph()
+	return ie
+}
+
+// Create index entry out of package control data and the package file.
+// Filename is the path of the package relative to the repository root.
+// Checksums calculated while reading the package are used, otherwise the
+// file is read once for all of them.
+func newIndexEntry(pf *PackageFile, filename string) (*IndexEntry, error) {
+	size, sums := int64(pf.FileSize()), pf.PackageChecksums()
+	if sums[HASH_MD5] == "" || sums[HASH_SHA256] == "" {
+		if pf.path == "" {
+			return nil, fmt.Errorf("package %s was not read fully, its checksums are not available", pf.ControlFile().Package())
+		}
+		f, err := os.Open(pf.path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		mh := newMultiHash(HASH_MD5, HASH_SHA256)
+		if size, err = io.Copy(mh, f); err != nil {
+			return nil, fmt.Errorf("%s: %s", pf.path, err.Error())
+		}
+		sums = mh.Sums()
+	}
+
+	ie := NewIndexEntry()
+	for _, name := range pf.ControlFile().Fields() {
+		ie.fields.Set(name, pf.ControlFile().Field(name))
+	}
+	ie.fields.Set("Filename", filepath.ToSlash(filename))
+	ie.fields.Set("Size", strconv.FormatInt(size, 10))
+	ie.fields.Set("MD5sum", sums[HASH_MD5])
+	ie.fields.Set("SHA256", sums[HASH_SHA256])
+	return ie, nil
+}
+
+// Package returns the binary package name
+func (ie *IndexEntry) Package() string {
+	return ie.fields.Get("Package")
+}
+
+// Version returns the package version
+func (ie *IndexEntry) Version() string {
+	return ie.fields.Get("Version")
+}
+
+// Architecture returns the package architecture
+func (ie *IndexEntry) Architecture() string {
+	return ie.fields.Get("Architecture")
+}
+
+// Source returns the source package name. It is the binary package name
+// if the Source field is missing.
+func (ie *IndexEntry) Source() string {
+	if source := strings.Fields(ie.fields.Get("Source")); len(source) > 0 {
+		return source[0]
+	}
+	return ie.Package()
+}
+
+// SourceVersion returns the source package version, if it differs from
+// the binary package version, or the package version otherwise.
+func (ie *IndexEntry) SourceVersion() string {
+	source := ie.fields.Get("Source")
+	if idx := strings.Index(source, "("); idx > -1 {
+		return strings.TrimSpace(strings.Trim(source[idx:], "()"))
+	}
+	return ie.Version()
+}
+
+// Filename returns the path of the package file relative to the repository root.
+func (ie *IndexEntry) Filename() string {
+	return ie.fields.Get("Filename")
+}
+
+// Size returns the size of the package file in bytes.
+func (ie *IndexEntry) Size() int64 {
+	size, _ := strconv.ParseInt(ie.fields.Get("Size"), 10, 64)
+	return size
+}
+
+// Field returns the value of any field. Field names are case-insensitive.
+func (ie *IndexEntry) Field(name string) string {
+	return ie.fields.Get(name)
+}
+
+// Fields returns field names in order of appearance.
+func (ie *IndexEntry) Fields() []string {
+	return ie.fields.Names()
+}
+
+// SetField sets the value of a field.
+func (ie *IndexEntry) SetField(name string, value string) *IndexEntry {
+	ie.fields.Set(name, value)
+	return ie
+}
+
+// String returns the stanza in deb822 format.
+func (ie *IndexEntry) String() string {
+	return ie.fields.String()
+}
+
+// PackagesIndex is a repository Packages index.
+type PackagesIndex struct {
+	entries []*IndexEntry
+}
+
+// NewPackagesIndex constructor
+func NewPackagesIndex() *PackagesIndex {
+	pi := new(PackagesIndex)
+	pi.entries = make([]*IndexEntry, 0)
+	return pi
+}
+
+// ParsePackagesIndex reads an uncompressed Packages index.
+func ParsePackagesIndex(r io.Reader) (*PackagesIndex, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	pi := NewPackagesIndex()
+	if err := pi.parse(data); err != nil {
+		return nil, err
+	}
+	return pi, nil
+}
+
+// OpenPackagesIndex reads a Packages index file, which may be compressed
+// with gzip or xz, as told by its extension. If the path is a directory,
+// the index is built out of the .deb files found in it.
+func OpenPackagesIndex(path string) (*PackagesIndex, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return PackagesIndexFromDir(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := indexReader(f, path)
+	if err != nil {
+		return nil, err
+	}
+	pi, err := ParsePackagesIndex(r)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			perr.File = path
+		}
+		return nil, err
+	}
+	return pi, nil
+}
+
+// Reader of an index file, decompressed by the extension of its name
+func indexReader(r io.Reader, name string) (io.Reader, error) {
+	switch filepath.Ext(name) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".xz":
+		return xz.NewReader(r, 0)
+	}
+	return r, nil
+}
+
+// PackagesIndexFromDir builds an index out of all .deb files in a directory
+// and its subdirectories. Filename fields are relative to the directory.
+func PackagesIndexFromDir(dir string) (*PackagesIndex, error) {
+	pi := NewPackagesIndex()
+	scanner := NewScanner().SetOptions(&PackageOptions{MetaOnly: true, Hash: HASH_MD5})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for res := range scanner.ScanDir(ctx, dir) {
+		if res.Err != nil {
+			return nil, fmt.Errorf("%s: %s", res.Path, res.Err.Error())
+		}
+		rel, err := filepath.Rel(dir, res.Path)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := newIndexEntry(res.Package, rel)
+		if err != nil {
+			return nil, err
+		}
+		pi.Add(entry)
+	}
+	pi.Sort()
+	return pi, nil
+}
+
+// Parse index data. Stanzas without Package field are rejected.
+func (pi *PackagesIndex) parse(data []byte) error {
+	paragraphs, err := parseParagraphs(data)
+	if err != nil {
+		return err
+	}
+	for idx, p := range paragraphs {
+		if p.Get("Package") == "" {
+			return fmt.Errorf("stanza %d has no Package field", idx+1)
+		}
+		pi.entries = append(pi.entries, &IndexEntry{fields: p})
+	}
+	return nil
+}
+
+// Add an entry to the index.
+func (pi *PackagesIndex) Add(entry *IndexEntry) *PackagesIndex {
+	pi.entries = append(pi.entries, entry)
+	return pi
+}
+
+// Remove all entries of a package with the version and architecture. Empty
+// version or architecture matches any.
+func (pi *PackagesIndex) Remove(name string, version string, arch string) *PackagesIndex {
+	entries := make([]*IndexEntry, 0, len(pi.entries))
+	for _, ie := range pi.entries {
+		if ie.Package() == name && (version == "" || ie.Version() == version) && (arch == "" || ie.Architecture() == arch) {
+			continue
+		}
+		entries = append(entries, ie)
+	}
+	pi.entries = entries
+	return pi
+}
+
+// Sort entries by package name, version and architecture.
+func (pi *PackagesIndex) Sort() *PackagesIndex {
+	sort.SliceStable(pi.entries, func(i, j int) bool {
+		a, b := pi.entries[i], pi.entries[j]
+		if a.Package() != b.Package() {
+			return a.Package() < b.Package()
+		}
+		if cmp := CompareVersions(a.Version(), b.Version()); cmp != 0 {
+			return cmp < 0
+		}
+		return a.Architecture() < b.Architecture()
+	})
+	return pi
+}
+
+// Entries returns all entries of the index.
+func (pi *PackagesIndex) Entries() []*IndexEntry {
+	return pi.entries
+}
+
+// Get returns the entry with the highest version of a package for the
+// architecture, or nil. Empty architecture matches any.
+func (pi *PackagesIndex) Get(name string, arch string) *IndexEntry {
+	var latest *IndexEntry
+	for _, ie := range pi.entries {
+		if ie.Package() != name || (arch != "" && ie.Architecture() != arch) {
+			continue
+		}
+		if latest == nil || CompareVersions(ie.Version(), latest.Version()) > 0 {
+			latest = ie
+		}
+	}
+	return latest
+}
+
+// WriteTo writes the index in deb822 format, stanzas separated by an empty line.
+func (pi *PackagesIndex) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for idx, ie := range pi.entries {
+		if idx > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(ie.String())
+	}
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the index in deb822 format.
+func (pi *PackagesIndex) Bytes() []byte {
+	var buf bytes.Buffer
+	pi.WriteTo(&buf)
+	return buf.Bytes()
+}
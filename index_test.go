@@ -0,0 +1,96 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestNewIndexEntry(t *testing.T) {
+	dir := t.TempDir()
+	debPath := testPackagePath(t, dir, testBuilder())
+	data, err := os.ReadFile(debPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5sum, sha256sum := md5.Sum(data), sha256.Sum256(data)
+
+	tests := []struct {
+		name string
+		opts *PackageOptions
+	}{
+		{"meta-data only", &PackageOptions{Hash: HASH_MD5, MetaOnly: true}},
+		{"full read", DefaultPackageOptions},
+		{"full read without MD5", &PackageOptions{Hash: HASH_SHA256}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := OpenPackageFile(debPath, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ie, err := newIndexEntry(pf, "pool/main/h/hello/hello_1.0-1_amd64.deb")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ie.Size() != int64(len(data)) || ie.Field("MD5sum") != hex.EncodeToString(md5sum[:]) || ie.Field("SHA256") != hex.EncodeToString(sha256sum[:]) {
+				t.Errorf("Size %s, MD5sum %s, SHA256 %s", ie.Field("Size"), ie.Field("MD5sum"), ie.Field("SHA256"))
+			}
+			if ie.Package() != "hello" || ie.Filename() != "pool/main/h/hello/hello_1.0-1_amd64.deb" {
+				t.Errorf("stanza %q", ie.String())
+			}
+		})
+	}
+
+	// Checksums of a package read fully do not need the file any more
+	full, err := OpenPackageFile(debPath, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := OpenPackageFile(debPath, &PackageOptions{Hash: HASH_MD5, MetaOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(debPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newIndexEntry(full, "hello.deb"); err != nil {
+		t.Error(err)
+	}
+	if _, err := newIndexEntry(meta, "hello.deb"); err == nil {
+		t.Errorf("index entry of a removed package created")
+	}
+	remote, err := OpenPackageReader(bytes.NewReader(data), int64(len(data)), &PackageOptions{Hash: HASH_MD5, MetaOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newIndexEntry(remote, "hello.deb"); err == nil {
+		t.Errorf("index entry of a package without checksums created")
+	}
+}
+
+func TestPackagesIndexFromDir(t *testing.T) {
+	dir := t.TempDir()
+	debPath := testPackagePath(t, dir, testBuilder())
+	data, err := os.ReadFile(debPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	pi, err := PackagesIndexFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := pi.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("%d entries", len(entries))
+	}
+	if ie := entries[0]; ie.Filename() != filepath.Base(debPath) || ie.Field("Size") != strconv.Itoa(len(data)) || ie.Field("SHA256") != hex.EncodeToString(sum[:]) {
+		t.Errorf("stanza %q", ie.String())
+	}
+}
@@ -0,0 +1,214 @@
+package deb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kinds of repository changes, in order of reporting
+var indexChangeKinds = []string{"added", "removed", "upgraded", "downgraded", "architecture", "relations"}
+
+// IndexChange is a change of a package between two repository indices.
+// Kind is one of "added", "removed", "upgraded", "downgraded", "architecture"
+// or "relations", the latter for packages of the same version with changed
+// relationship fields.
+type IndexChange struct {
+	Package         string           `json:"package"`
+	Kind            string           `json:"kind"`
+	Architecture    string           `json:"architecture"`
+	OldArchitecture string           `json:"old_architecture,omitempty"`
+	OldVersion      string           `json:"old_version,omitempty"`
+	NewVersion      string           `json:"new_version,omitempty"`
+	Relations       []RelationChange `json:"relations,omitempty"`
+}
+
+// IndexDiff describes differences between two repository indices.
+type IndexDiff struct {
+	Changes []IndexChange `json:"changes"`
+}
+
+// Latest entries of an index by package name and architecture
+func latestEntries(pi *PackagesIndex) map[string]map[string]*IndexEntry {
+	latest := make(map[string]map[string]*IndexEntry)
+	for _, ie := range pi.Entries() {
+		archs, ok := latest[ie.Package()]
+		if !ok {
+			archs = make(map[string]*IndexEntry)
+			latest[ie.Package()] = archs
+		}
+		if cur, ok := archs[ie.Architecture()]; !ok || CompareVersions(ie.Version(), cur.Version()) > 0 {
+			archs[ie.Architecture()] = ie
+		}
+	}
+	return latest
+}
+
+// Sorted architectures of a package
+func entryArchs(archs map[string]*IndexEntry) []string {
+	names := make([]string, 0, len(archs))
+	for arch := range archs {
+		names = append(names, arch)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DiffIndices compares two repository snapshots, e.g. staging and production.
+// Only the highest version of each package and architecture is taken into
+// account. A package moving to another architecture, e.g. from amd64 to all,
+// is reported as an architecture change rather than removed and added.
+func DiffIndices(a *PackagesIndex, b *PackagesIndex) *IndexDiff {
+	d := &IndexDiff{Changes: make([]IndexChange, 0)}
+	old, new := latestEntries(a), latestEntries(b)
+
+	names := make([]string, 0, len(old)+len(new))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oarchs, narchs := old[name], new[name]
+		oonly, nonly := make([]string, 0), make([]string, 0)
+		for _, arch := range entryArchs(oarchs) {
+			if nie, ok := narchs[arch]; ok {
+				d.compare(oarchs[arch], nie)
+			} else {
+				oonly = append(oonly, arch)
+			}
+		}
+		for _, arch := range entryArchs(narchs) {
+			if _, ok := oarchs[arch]; !ok {
+				nonly = append(nonly, arch)
+			}
+		}
+
+		switch {
+		case len(oonly) > 0 && len(nonly) == 1:
+			for _, arch := range oonly {
+				d.compare(oarchs[arch], narchs[nonly[0]])
+			}
+		case len(oonly) == 1 && len(nonly) > 1:
+			for _, arch := range nonly {
+				d.compare(oarchs[oonly[0]], narchs[arch])
+			}
+		default:
+			for _, arch := range oonly {
+				d.Changes = append(d.Changes, IndexChange{Package: name, Kind: "removed", Architecture: arch, OldVersion: oarchs[arch].Version()})
+			}
+			for _, arch := range nonly {
+				d.Changes = append(d.Changes, IndexChange{Package: name, Kind: "added", Architecture: arch, NewVersion: narchs[arch].Version()})
+			}
+		}
+	}
+	return d
+}
+
+// Compare two entries of a package
+func (d *IndexDiff) compare(oie *IndexEntry, nie *IndexEntry) {
+	ic := IndexChange{
+		Package:      nie.Package(),
+		Architecture: nie.Architecture(),
+		OldVersion:   oie.Version(),
+		NewVersion:   nie.Version(),
+		Relations:    relationChanges(oie, nie),
+	}
+	cmp := CompareVersions(oie.Version(), nie.Version())
+	switch {
+	case oie.Architecture() != nie.Architecture():
+		ic.Kind = "architecture"
+		ic.OldArchitecture = oie.Architecture()
+	case cmp < 0:
+		ic.Kind = "upgraded"
+	case cmp > 0:
+		ic.Kind = "downgraded"
+	case len(ic.Relations) > 0:
+		ic.Kind = "relations"
+	default:
+		return
+	}
+	d.Changes = append(d.Changes, ic)
+}
+
+// Count returns the number of changes of a kind.
+func (d *IndexDiff) Count(kind string) int {
+	count := 0
+	for _, ic := range d.Changes {
+		if ic.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// Empty returns true if indices do not differ.
+func (d *IndexDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// WriteJSON writes the diff as a JSON object.
+func (d *IndexDiff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteText writes a report of the changes grouped by kind, followed by a summary.
+func (d *IndexDiff) WriteText(w io.Writer) error {
+	titles := map[string]string{
+		"added":        "Added",
+		"removed":      "Removed",
+		"upgraded":     "Upgraded",
+		"downgraded":   "Downgraded",
+		"architecture": "Architecture changes",
+		"relations":    "Relation changes",
+	}
+	var out strings.Builder
+	summary := make([]string, 0)
+	for _, kind := range indexChangeKinds {
+		count := d.Count(kind)
+		summary = append(summary, fmt.Sprintf("%d %s", count, kind))
+		if count == 0 {
+			continue
+		}
+		out.WriteString(titles[kind] + ":\n")
+		for _, ic := range d.Changes {
+			if ic.Kind != kind {
+				continue
+			}
+			switch kind {
+			case "added":
+				out.WriteString(fmt.Sprintf("  %s %s [%s]\n", ic.Package, ic.NewVersion, ic.Architecture))
+			case "removed":
+				out.WriteString(fmt.Sprintf("  %s %s [%s]\n", ic.Package, ic.OldVersion, ic.Architecture))
+			case "architecture":
+				out.WriteString(fmt.Sprintf("  %s %s -> %s [%s -> %s]\n", ic.Package, ic.OldVersion, ic.NewVersion, ic.OldArchitecture, ic.Architecture))
+			case "relations":
+				out.WriteString(fmt.Sprintf("  %s %s [%s]\n", ic.Package, ic.NewVersion, ic.Architecture))
+			default:
+				out.WriteString(fmt.Sprintf("  %s %s -> %s [%s]\n", ic.Package, ic.OldVersion, ic.NewVersion, ic.Architecture))
+			}
+			for _, rc := range ic.Relations {
+				for _, rel := range rc.Added {
+					out.WriteString(fmt.Sprintf("      + %s: %s\n", rc.Field, rel))
+				}
+				for _, rel := range rc.Removed {
+					out.WriteString(fmt.Sprintf("      - %s: %s\n", rc.Field, rel))
+				}
+			}
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("Summary: " + strings.Join(summary, ", ") + "\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,125 @@
+package deb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ChecksumMismatch is a file with a checksum differing from the expected one.
+type ChecksumMismatch struct {
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// IntegrityReport lists differences between the md5sums file of a package
+// and the files of its data archive.
+type IntegrityReport struct {
+	// The package has files, but no md5sums file. Other lists are empty then.
+	NoMd5sums bool `json:"no_md5sums"`
+	// Files listed in md5sums, but missing from the data archive
+	Missing []string `json:"missing"`
+	// Regular files of the data archive missing from md5sums, except conffiles
+	Unlisted []string `json:"unlisted"`
+	// Files with a checksum differing from md5sums
+	Mismatched []ChecksumMismatch `json:"mismatched"`
+}
+
+// OK returns true if md5sums matches the data archive.
+func (ir *IntegrityReport) OK() bool {
+	return !ir.NoMd5sums && len(ir.Missing) == 0 && len(ir.Unlisted) == 0 && len(ir.Mismatched) == 0
+}
+
+// WriteJSON writes the report as a JSON object.
+func (ir *IntegrityReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ir)
+}
+
+// WriteText writes one line per problem, or "OK".
+func (ir *IntegrityReport) WriteText(w io.Writer) error {
+	var out strings.Builder
+	if ir.NoMd5sums {
+		out.WriteString("no md5sums file\n")
+	}
+	for _, path := range ir.Missing {
+		out.WriteString(fmt.Sprintf("missing: %s\n", path))
+	}
+	for _, path := range ir.Unlisted {
+		out.WriteString(fmt.Sprintf("unlisted: %s\n", path))
+	}
+	for _, m := range ir.Mismatched {
+		out.WriteString(fmt.Sprintf("mismatch: %s: expected %s, got %s\n", m.Path, m.Expected, m.Actual))
+	}
+	if ir.OK() {
+		out.WriteString("OK\n")
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// VerifyIntegrity compares md5sums with the files of the data archive. It
+// needs MD5 checksums of files, calculated with HASH_MD5 as the hash or one
+// of the additional hashes of the package options. The data archive must
+// be read, not only meta-data.
+func (c *PackageFile) VerifyIntegrity() (*IntegrityReport, error) {
+	report := &IntegrityReport{Missing: make([]string, 0), Unlisted: make([]string, 0), Mismatched: make([]ChecksumMismatch, 0)}
+	if len(c.fileMd5Checksums) == 0 && (c.format == PACKAGE_FORMAT_IPK || c.PackageType() == PACKAGE_TYPE_UDEB) {
+		return report, nil // opkg packages and udebs have no md5sums
+	}
+	if len(c.files) == 0 {
+		return nil, fmt.Errorf("data archive was not processed")
+	}
+	if len(c.fileMd5Checksums) == 0 {
+		for _, fi := range c.files {
+			report.NoMd5sums = report.NoMd5sums || fi.Mode().IsRegular()
+		}
+		return report, nil
+	}
+
+	// Names of the archive and link targets may differ in form, e.g.
+	// "./usr/bin/hello" and "usr/bin/hello", so both are normalised
+	calculated := make(map[string]string)
+	for name, sums := range c.fileChecksums {
+		calculated[absPath(name)] = sums[HASH_MD5]
+	}
+	actual := make(map[string]string)
+	for _, fi := range c.files {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		name := absPath(fi.Name())
+		if fi.Linkname() != "" {
+			name = absPath(fi.Linkname()) // hard link, the content is of the target
+		}
+		sum := calculated[name]
+		if sum == "" {
+			return nil, fmt.Errorf("MD5 checksums of files were not calculated")
+		}
+		actual[strings.TrimPrefix(absPath(fi.Name()), "/")] = sum
+	}
+
+	for path, expected := range c.fileMd5Checksums {
+		sum, ok := actual[path]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, path)
+		case !strings.EqualFold(sum, expected):
+			report.Mismatched = append(report.Mismatched, ChecksumMismatch{Path: path, Expected: expected, Actual: sum})
+		}
+	}
+	for path := range actual {
+		if _, ok := c.fileMd5Checksums[path]; !ok && c.conffiles.Get(absPath(path)) == nil {
+			report.Unlisted = append(report.Unlisted, path)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unlisted)
+	sort.Slice(report.Mismatched, func(i, j int) bool { return report.Mismatched[i].Path < report.Mismatched[j].Path })
+	return report, nil
+}
@@ -0,0 +1,77 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestVerifyIntegrityRead(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBuilder().WriteDeb(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opts *PackageOptions
+		err  string
+	}{
+		{"full read", &PackageOptions{Hash: HASH_MD5, RecalculateChecksums: true}, ""},
+		{"MD5 as additional hash", &PackageOptions{Hash: HASH_SHA256, Hashes: []int{HASH_MD5}, RecalculateChecksums: true}, ""},
+		{"meta-data only", &PackageOptions{Hash: HASH_MD5, MetaOnly: true, RecalculateChecksums: true}, "not processed"},
+		{"checksums not recalculated", &PackageOptions{Hash: HASH_MD5}, "not calculated"},
+		{"MD5 not calculated", &PackageOptions{Hash: HASH_SHA256, RecalculateChecksums: true}, "not calculated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := NewPackageFileReader(bytes.NewReader(buf.Bytes())).SetOptions(tt.opts).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := pf.VerifyIntegrity()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, expected %q, report %+v", err, tt.err, report)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.OK() {
+				t.Errorf("intact package reported as %+v", report)
+			}
+		})
+	}
+}
+
+func TestVerifyIntegrityHardLinks(t *testing.T) {
+	content := []byte("#!/bin/sh\necho hello\n")
+	sum := md5.Sum(content)
+	md5sums := fmt.Sprintf("%[1]x  usr/bin/hello\n%[1]x  usr/bin/hello-link\n", sum)
+	control := testTarGz(
+		testTarEntry{tar.Header{Name: "./control", Mode: 0644, Typeflag: tar.TypeReg}, []byte("Package: hello\nVersion: 1.0-1\nArchitecture: amd64\n")},
+		testTarEntry{tar.Header{Name: "./md5sums", Mode: 0644, Typeflag: tar.TypeReg}, []byte(md5sums)})
+
+	// Link targets are named with or without "./", whatever the file names
+	for _, names := range [][2]string{{"./usr/bin/hello", "usr/bin/hello"}, {"usr/bin/hello", "./usr/bin/hello"}, {"./usr/bin/hello", "/usr/bin/hello"}} {
+		data := testTarGz(
+			testTarEntry{tar.Header{Name: names[0], Mode: 0755, Typeflag: tar.TypeReg}, content},
+			testTarEntry{tar.Header{Name: "./usr/bin/hello-link", Mode: 0755, Typeflag: tar.TypeLink, Linkname: names[1]}, nil})
+		pf, err := OpenPackageFile(testRawDebPath(t, t.TempDir(), control, data), &PackageOptions{Hash: HASH_MD5, RecalculateChecksums: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := pf.VerifyIntegrity()
+		if err != nil {
+			t.Errorf("file %s, link to %s: %v", names[0], names[1], err)
+			continue
+		}
+		if !report.OK() {
+			t.Errorf("file %s, link to %s: %+v", names[0], names[1], report)
+		}
+	}
+}
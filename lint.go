@@ -0,0 +1,355 @@
+package deb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintSeverity of a finding
+type LintSeverity int
+
+const (
+	LINT_ERROR LintSeverity = iota
+	LINT_WARNING
+	LINT_INFO
+)
+
+func (ls LintSeverity) String() string {
+	switch ls {
+	case LINT_ERROR:
+		return "error"
+	case LINT_WARNING:
+		return "warning"
+	case LINT_INFO:
+		return "info"
+	}
+	return "unknown"
+}
+
+// MarshalJSON renders severity by its name
+func (ls LintSeverity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ls.String())
+}
+
+// LintFinding is a problem found in a package by a check.
+type LintFinding struct {
+	Severity LintSeverity `json:"severity"`
+	Package  string       `json:"package"`
+	Tag      string       `json:"tag"`
+	Location string       `json:"location,omitempty"`
+	Message  string       `json:"message,omitempty"`
+}
+
+// LintReport collects findings of all checks over a package.
+type LintReport struct {
+	pkg      string
+	findings []LintFinding
+}
+
+// NewLintReport constructor
+func NewLintReport(pkg string) *LintReport {
+	lr := new(LintReport)
+	lr.pkg = pkg
+	lr.findings = make([]LintFinding, 0)
+	return lr
+}
+
+// Add a finding. Location is usually a path in the package or a control member name.
+func (lr *LintReport) Add(severity LintSeverity, tag string, location string, format string, args ...interface{}) *LintReport {
+	lr.findings = append(lr.findings, LintFinding{
+		Severity: severity, Package: lr.pkg, Tag: tag, Location: location, Message: fmt.Sprintf(format, args...),
+	})
+	return lr
+}
+
+// Findings returns all findings in order they were reported.
+func (lr *LintReport) Findings() []LintFinding {
+	return lr.findings
+}
+
+// Count returns number of findings of a severity.
+func (lr *LintReport) Count(severity LintSeverity) int {
+	cnt := 0
+	for _, f := range lr.findings {
+		if f.Severity == severity {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// HasErrors returns true if any finding is an error.
+func (lr *LintReport) HasErrors() bool {
+	return lr.Count(LINT_ERROR) > 0
+}
+
+// WriteText writes findings in lintian-like format, one per line:
+//
+//	E: package: tag location (message)
+func (lr *LintReport) WriteText(w io.Writer) error {
+	for _, f := range lr.findings {
+		line := fmt.Sprintf("%c: %s: %s", strings.ToUpper(f.Severity.String())[0], f.Package, f.Tag)
+		if f.Location != "" {
+			line += " " + f.Location
+		}
+		if f.Message != "" {
+			line += " (" + f.Message + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes findings as a JSON array.
+func (lr *LintReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(lr.findings)
+}
+
+// LintCheck inspects a package and adds its findings to the report.
+type LintCheck func(pf *PackageFile, report *LintReport)
+
+var lintChecks = make(map[string]LintCheck)
+
+// RegisterLintCheck registers a check globally, so every new Linter runs it.
+// A check with the same name is replaced.
+func RegisterLintCheck(name string, check LintCheck) {
+	lintChecks[name] = check
+}
+
+// Linter runs static checks over packages.
+type Linter struct {
+	checks map[string]LintCheck
+}
+
+// NewLinter creates a linter with all globally registered checks.
+func NewLinter() *Linter {
+	l := new(Linter)
+	l.checks = make(map[string]LintCheck)
+	for name, check := range lintChecks {
+		l.checks[name] = check
+	}
+	return l
+}
+
+// Register a check for this linter only.
+func (l *Linter) Register(name string, check LintCheck) *Linter {
+	l.checks[name] = check
+	return l
+}
+
+// Disable a check by its name.
+func (l *Linter) Disable(name string) *Linter {
+	delete(l.checks, name)
+	return l
+}
+
+// Checks returns sorted names of the checks.
+func (l *Linter) Checks() []string {
+	names := make([]string, 0, len(l.checks))
+	for name := range l.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check runs all checks, in order of their names, over the package.
+// Checks of the data archive contents are skipped if files were not processed.
+func (l *Linter) Check(pf *PackageFile) *LintReport {
+	report := NewLintReport(pf.ControlFile().Package())
+	for _, name := range l.Checks() {
+		l.checks[name](pf, report)
+	}
+	return report
+}
+
+func init() {
+	RegisterLintCheck("control", lintControl)
+	RegisterLintCheck("md5sums", lintMd5sums)
+	RegisterLintCheck("fhs", lintFHS)
+	RegisterLintCheck("permissions", lintPermissions)
+	RegisterLintCheck("conffiles", lintConffiles)
+	RegisterLintCheck("scripts", lintScripts)
+	RegisterLintCheck("installed-size", lintInstalledSize)
+	RegisterLintCheck("copyright", lintCopyright)
+}
+
+// Mandatory control fields and a valid version
+func lintControl(pf *PackageFile, report *LintReport) {
+	cf := pf.ControlFile()
+	// Raw values, as parsed ones are amended, e.g. Description gets a full stop
+	for _, name := range []string{"Package", "Version", "Architecture", "Maintainer", "Description"} {
+		if strings.TrimSpace(cf.Field(name)) == "" {
+			report.Add(LINT_ERROR, "missing-control-field", "control", "%s", name)
+		}
+	}
+	if cf.Version() != "" {
+		if _, err := ParseVersion(cf.Version()); err != nil {
+			report.Add(LINT_ERROR, "invalid-version", "control", "%s", err.Error())
+		}
+	}
+}
+
+// Regular files shipped without md5sums
+func lintMd5sums(pf *PackageFile, report *LintReport) {
+	if len(pf.fileMd5Checksums) > 0 || pf.Format() == PACKAGE_FORMAT_IPK || pf.PackageType() == PACKAGE_TYPE_UDEB {
+		return
+	}
+	for _, fi := range pf.Files() {
+		if fi.Mode().IsRegular() {
+			report.Add(LINT_ERROR, "missing-md5sums", "md5sums", "")
+			return
+		}
+	}
+}
+
+var fhsTopDirs = []string{"bin", "boot", "etc", "lib", "lib32", "lib64", "libx32", "opt", "sbin", "srv", "usr", "var"}
+var fhsUsrDirs = []string{"bin", "games", "include", "lib", "lib32", "lib64", "libexec", "libx32", "sbin", "share", "src"}
+var fhsForbiddenDirs = []string{"dev", "home", "media", "mnt", "proc", "root", "run", "sys", "tmp", "usr/local"}
+
+// Files outside of the Filesystem Hierarchy Standard
+func lintFHS(pf *PackageFile, report *LintReport) {
+	reported := make(map[string]bool)
+	for _, fi := range pf.Files() {
+		path := strings.TrimPrefix(absPath(fi.Name()), "/")
+		parts := strings.SplitN(path, "/", 3)
+		if path == "" {
+			continue
+		}
+
+		for _, dir := range fhsForbiddenDirs {
+			if strings.HasPrefix(path, dir+"/") && !reported[dir] {
+				reported[dir] = true
+				report.Add(LINT_ERROR, "file-outside-fhs", path, "files must not be shipped in /%s", dir)
+			}
+		}
+		if !in(parts[0], fhsTopDirs) && !in(parts[0], fhsForbiddenDirs) && !reported[parts[0]] {
+			reported[parts[0]] = true
+			report.Add(LINT_WARNING, "file-outside-fhs", path, "non-standard top-level directory /%s", parts[0])
+		}
+		if parts[0] == "usr" && len(parts) > 1 && !in(parts[1], fhsUsrDirs) && parts[1] != "local" && !reported["usr/"+parts[1]] {
+			reported["usr/"+parts[1]] = true
+			report.Add(LINT_WARNING, "file-outside-fhs", path, "non-standard directory /usr/%s", parts[1])
+		}
+	}
+}
+
+// Setuid, setgid and world-writable files
+func lintPermissions(pf *PackageFile, report *LintReport) {
+	for _, fi := range pf.Files() {
+		mode := fi.Mode()
+		path := strings.TrimPrefix(absPath(fi.Name()), "/")
+		if mode&os.ModeSymlink != 0 {
+			continue
+		}
+		if mode&os.ModeSetuid != 0 {
+			report.Add(LINT_WARNING, "setuid-binary", path, "%04o %s/%s", fi.Perm(), fi.Owner(), fi.Group())
+		}
+		if mode&os.ModeSetgid != 0 && !mode.IsDir() {
+			report.Add(LINT_WARNING, "setgid-binary", path, "%04o %s/%s", fi.Perm(), fi.Owner(), fi.Group())
+		}
+		if mode.Perm()&0002 != 0 && !(mode.IsDir() && mode&os.ModeSticky != 0) {
+			report.Add(LINT_ERROR, "world-writable-file", path, "%04o", fi.Perm())
+		}
+	}
+}
+
+// Conffiles must be under /etc and shipped
+func lintConffiles(pf *PackageFile, report *LintReport) {
+	for _, err := range pf.CheckConffiles() {
+		// Only the location is checked if the data archive was not read
+		if cerr, ok := err.(*ConffileError); ok {
+			report.Add(LINT_ERROR, cerr.Problem, cerr.Path, "")
+		}
+	}
+}
+
+var setErrexit = regexp.MustCompile(`(?m)^\s*set\s+(-[a-zA-Z]*e|-o\s+errexit)`)
+
+// Shell maintainer scripts must stop on errors
+func lintScripts(pf *PackageFile, report *LintReport) {
+	scripts := map[string]string{
+		"preinst": pf.PreInstallScript(), "postinst": pf.PostInstallScript(),
+		"prerm": pf.PreUninstallScript(), "postrm": pf.PostUninstallScript(), "config": pf.ConfigScript(),
+	}
+	for _, name := range []string{"config", "postinst", "postrm", "preinst", "prerm"} {
+		script := scripts[name]
+		if script == "" {
+			continue
+		}
+		shebang := strings.Fields(strings.TrimPrefix(strings.SplitN(script, "\n", 2)[0], "#!"))
+		if !strings.HasPrefix(script, "#!") || len(shebang) == 0 {
+			report.Add(LINT_ERROR, "maintainer-script-without-interpreter", name, "")
+			continue
+		}
+		shell := shebang[0][strings.LastIndex(shebang[0], "/")+1:]
+		if !in(shell, []string{"sh", "bash", "dash"}) {
+			continue
+		}
+		if len(shebang) > 1 && strings.HasPrefix(shebang[1], "-") && strings.Contains(shebang[1], "e") {
+			continue
+		}
+		if !setErrexit.MatchString(script) {
+			report.Add(LINT_WARNING, "maintainer-script-without-set-e", name, "")
+		}
+	}
+}
+
+// InstalledSizeKiB estimates Installed-Size of the package contents the way
+// dpkg-gencontrol does: regular files and symlinks are counted by their size
+// in KiB rounded up, everything else counts as 1 KiB. Hardlinks have no size
+// in the data archive and are not counted.
+func (c *PackageFile) InstalledSizeKiB() int64 {
+	var size int64
+	for _, fi := range c.files {
+		switch {
+		case fi.Mode().IsRegular():
+			size += (fi.Size() + 1023) / 1024
+		case fi.Mode()&os.ModeSymlink != 0:
+			size += (int64(len(fi.Linkname())) + 1023) / 1024
+		default:
+			size++
+		}
+	}
+	return size
+}
+
+// Installed-Size should be the size of the contents
+func lintInstalledSize(pf *PackageFile, report *LintReport) {
+	if len(pf.Files()) == 0 {
+		return
+	}
+	declared, actual := int64(pf.ControlFile().InstalledSize()), pf.InstalledSizeKiB()
+	diff := declared - actual
+	if diff < 0 {
+		diff = -diff
+	}
+	if declared == 0 {
+		report.Add(LINT_WARNING, "missing-installed-size", "control", "contents take %d KiB", actual)
+	} else if diff*10 > actual {
+		report.Add(LINT_WARNING, "installed-size-mismatch", "control", "declared %d KiB, contents take %d KiB", declared, actual)
+	}
+}
+
+// Copyright file must be shipped
+func lintCopyright(pf *PackageFile, report *LintReport) {
+	if len(pf.Files()) == 0 || pf.CopyrightFile().Text() != "" {
+		return
+	}
+	docdir := "/usr/share/doc/" + pf.ControlFile().Package()
+	for _, fi := range pf.Files() {
+		if absPath(fi.Name()) == docdir && fi.Mode()&os.ModeSymlink != 0 {
+			return // Documentation directory is shared with another package
+		}
+	}
+	report.Add(LINT_ERROR, "no-copyright-file", strings.TrimPrefix(docdir, "/")+"/copyright", "")
+}
@@ -0,0 +1,72 @@
+package deb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Tags of conffile findings
+func conffileTags(report *LintReport) []string {
+	tags := make([]string, 0)
+	for _, finding := range report.Findings() {
+		if finding.Tag == CONFFILE_NOT_IN_ETC || finding.Tag == CONFFILE_NOT_SHIPPED ||
+			finding.Tag == CONFFILE_REMOVED_BUT_SHIPPED || finding.Tag == CONFFILE_NOT_REGULAR {
+			tags = append(tags, finding.Tag+" "+finding.Location)
+		}
+	}
+	return tags
+}
+
+func TestLintConffiles(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBuilder().AddConffile("/opt/hello/hello.conf", 0644, []byte("x\n")).WriteDeb(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, metaonly := range []bool{false, true} {
+		pf, err := NewPackageFileReader(bytes.NewReader(buf.Bytes())).SetMetaonly(metaonly).SetHash(HASH_MD5).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags := conffileTags(NewLinter().Check(pf))
+		if len(tags) != 1 || tags[0] != CONFFILE_NOT_IN_ETC+" /opt/hello/hello.conf" {
+			t.Errorf("meta-only %v: findings %q", metaonly, tags)
+		}
+	}
+}
+
+// Fields reported missing
+func missingFields(report *LintReport) []string {
+	fields := make([]string, 0)
+	for _, finding := range report.Findings() {
+		if finding.Tag == "missing-control-field" {
+			fields = append(fields, finding.Message)
+		}
+	}
+	return fields
+}
+
+func TestLintControlMissingFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		control string
+		missing []string
+	}{
+		{"all fields", "Package: hello\nVersion: 1.0-1\nArchitecture: amd64\nMaintainer: Jane Doe <jane@example.org>\nDescription: greeting\n", []string{}},
+		{"empty description", "Package: hello\nVersion: 1.0-1\nArchitecture: amd64\nMaintainer: Jane Doe <jane@example.org>\nDescription:\n", []string{"Description"}},
+		{"blank description", "Package: hello\nVersion: 1.0-1\nArchitecture: amd64\nMaintainer: Jane Doe <jane@example.org>\nDescription:   \n", []string{"Description"}},
+		{"no maintainer and description", "Package: hello\nVersion: 1.0-1\nArchitecture: amd64\n", []string{"Maintainer", "Description"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := OpenPackageFile(testRawPackagePath(t, t.TempDir(), tt.control), DefaultPackageOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			missing := missingFields(NewLinter().Check(pf))
+			if strings.Join(missing, ",") != strings.Join(tt.missing, ",") {
+				t.Errorf("missing fields %q, expected %q", missing, tt.missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package deb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PackageOpener opens packages of URIs with a particular scheme.
+type PackageOpener interface {
+	OpenPackage(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error)
+}
+
+// PackageOpenerFunc is a function used as a PackageOpener.
+type PackageOpenerFunc func(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error)
+
+// OpenPackage calls the function.
+func (f PackageOpenerFunc) OpenPackage(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error) {
+	return f(ctx, uri, opts)
+}
+
+// Openers by URI scheme. Paths without a scheme are opened as "file".
+var openers = struct {
+	sync.RWMutex
+	schemes map[string]PackageOpener
+}{schemes: map[string]PackageOpener{
+	"file":  PackageOpenerFunc(openFileURI),
+	"http":  PackageOpenerFunc(OpenPackageURL),
+	"https": PackageOpenerFunc(OpenPackageURL),
+}}
+
+// RegisterOpener sets the opener of URIs with a scheme, e.g. "s3", replacing
+// the previous one. A nil opener removes the scheme.
+func RegisterOpener(scheme string, opener PackageOpener) {
+	openers.Lock()
+	defer openers.Unlock()
+	scheme = strings.ToLower(scheme)
+	if opener == nil {
+		delete(openers.schemes, scheme)
+	} else {
+		openers.schemes[scheme] = opener
+	}
+}
+
+// Opener returns the opener of a scheme, or nil.
+func Opener(scheme string) PackageOpener {
+	openers.RLock()
+	defer openers.RUnlock()
+	return openers.schemes[strings.ToLower(scheme)]
+}
+
+// Scheme of a URI, or "file" for paths
+func uriScheme(uri string) string {
+	if idx := strings.Index(uri, "://"); idx > 0 {
+		return strings.ToLower(uri[:idx])
+	}
+	return "file"
+}
+
+// OpenPackageContext opens a package with the opener of the scheme of the URI.
+// Paths without a scheme are local files. Packages named *.ipk are opkg packages,
+// the type of packages named *.udeb or *.ddeb is known from the name.
+func OpenPackageContext(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error) {
+	scheme := uriScheme(uri)
+	opener := Opener(scheme)
+	if opener == nil {
+		return nil, fmt.Errorf("%s: no opener for scheme '%s'", uri, scheme)
+	}
+	pf, err := opener.OpenPackage(ctx, uri, opts)
+	if err == nil {
+		switch strings.ToLower(path.Ext(uri)) {
+		case ".ipk":
+			pf.format = PACKAGE_FORMAT_IPK
+		case ".udeb":
+			pf.pkgType = PACKAGE_TYPE_UDEB
+		case ".ddeb":
+			pf.pkgType = PACKAGE_TYPE_DDEB
+		}
+	}
+	return pf, err
+}
+
+// Open a local file of a path or a file:// URI
+func openFileURI(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error) {
+	if uriScheme(uri) == "file" && strings.Contains(uri, "://") {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("%s: remote file hosts are not supported", uri)
+		}
+		uri = u.Path
+	}
+	return openPackagePath(uri, opts)
+}
+
+// OpenPackageReader reads a package of a size from random access storage,
+// e.g. an in-memory blob or an object store. If only meta-data is
+// requested, the data archive is not read at all.
+func OpenPackageReader(r io.ReaderAt, size int64, opts *PackageOptions) (*PackageFile, error) {
+	length := size
+	if opts.MetaOnly {
+		var err error
+		if length, err = arMetaLength(r, size); err != nil {
+			return nil, err
+		}
+	}
+	p, err := NewPackageFileReader(io.NewSectionReader(r, 0, length)).SetOptions(opts).Read()
+	if err != nil {
+		return nil, err
+	}
+	p.fileSize = uint64(size)
+	return p, nil
+}
+
+// Length of the ar archive up to the data member, or the whole size of
+// other archives
+func arMetaLength(r io.ReaderAt, size int64) (int64, error) {
+	magic := make([]byte, len(arMagic))
+	if _, err := r.ReadAt(magic, 0); err == nil && bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) {
+		return size, nil // gzipped tar archive of opkg, read completely
+	} else if err != nil || string(magic) != arMagic {
+		return 0, fmt.Errorf("not an ar archive")
+	}
+	offset := int64(len(arMagic))
+	header := make([]byte, arHeaderSize)
+	for offset+arHeaderSize <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return 0, err
+		}
+		name, length, err := arMemberHeader(header)
+		if err != nil {
+			return 0, err
+		}
+		if strings.HasPrefix(name, "data.") {
+			break
+		}
+		offset += arHeaderSize + length + length%2
+	}
+	if offset > size {
+		offset = size
+	}
+	return offset, nil
+}
+
+// Name and size of an ar member from its header
+func arMemberHeader(header []byte) (string, int64, error) {
+	name := strings.Trim(strings.TrimSpace(string(header[0:16])), "/")
+	if !bytes.Equal(header[58:60], []byte("`\n")) {
+		return "", 0, fmt.Errorf("malformed ar header of '%s'", name)
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(string(header[48:58])), 10, 64)
+	if err != nil || size < 0 {
+		return "", 0, fmt.Errorf("malformed ar header of '%s'", name)
+	}
+	return name, size, nil
+}
@@ -0,0 +1,105 @@
+package deb
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Opener of an in-memory package, recording the URIs it opens
+type testOpener struct {
+	content []byte
+	uris    []string
+}
+
+func (to *testOpener) OpenPackage(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error) {
+	to.uris = append(to.uris, uri)
+	return OpenPackageReader(bytes.NewReader(to.content), int64(len(to.content)), opts)
+}
+
+func TestRegisterOpener(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBuilder().WriteDeb(&buf); err != nil {
+		t.Fatal(err)
+	}
+	opener := &testOpener{content: buf.Bytes()}
+	RegisterOpener("Mem", opener)
+	t.Cleanup(func() { RegisterOpener("mem", nil) })
+
+	if Opener("MEM") != opener {
+		t.Errorf("opener of the scheme is not registered case-insensitively")
+	}
+	pf, err := OpenPackageContext(context.Background(), "mem://bucket/hello_1.0-1_amd64.udeb", DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opener.uris) != 1 || opener.uris[0] != "mem://bucket/hello_1.0-1_amd64.udeb" {
+		t.Errorf("opener called with %q", opener.uris)
+	}
+	if pf.ControlFile().Package() != "hello" {
+		t.Errorf("package %q", pf.ControlFile().Package())
+	}
+	if pf.PackageType() != PACKAGE_TYPE_UDEB {
+		t.Errorf("package type %q", pf.PackageType())
+	}
+
+	RegisterOpener("mem", nil)
+	if _, err := OpenPackageContext(context.Background(), "mem://bucket/hello.deb", DefaultPackageOptions); err == nil || !strings.Contains(err.Error(), "no opener") {
+		t.Errorf("removed scheme opened: %v", err)
+	}
+	if len(opener.uris) != 1 {
+		t.Errorf("removed opener called")
+	}
+}
+
+func TestOpenPackageContextFile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBuilder().WriteIPK(&buf); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "hello_1.0-1_amd64.ipk")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, uri := range []string{path, "file://" + path, "file://localhost" + path} {
+		pf, err := OpenPackageContext(context.Background(), uri, DefaultPackageOptions)
+		if err != nil {
+			t.Errorf("%s: %v", uri, err)
+			continue
+		}
+		if pf.Format() != PACKAGE_FORMAT_IPK || pf.ControlFile().Package() != "hello" {
+			t.Errorf("%s: %s package %q", uri, pf.Format(), pf.ControlFile().Package())
+		}
+	}
+	if _, err := OpenPackageContext(context.Background(), "file://example.org"+path, DefaultPackageOptions); err == nil {
+		t.Errorf("file of a remote host opened")
+	}
+}
+
+func TestOpenPackageReaderMetaOnly(t *testing.T) {
+	content := testLargePackage(t, testWriteDeb)
+	opts := &PackageOptions{Hash: HASH_MD5, MetaOnly: true}
+	pf, err := OpenPackageReader(bytes.NewReader(content), int64(len(content)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf.ControlFile().Package() != "hello" || len(pf.Files()) != 0 {
+		t.Errorf("package %q with %d files", pf.ControlFile().Package(), len(pf.Files()))
+	}
+	if pf.FileSize() != uint64(len(content)) {
+		t.Errorf("size %d, expected %d", pf.FileSize(), len(content))
+	}
+	length, err := arMetaLength(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length >= int64(len(content))/2 {
+		t.Errorf("meta-data length %d of %d bytes includes the data archive", length, len(content))
+	}
+	if _, err := OpenPackageReader(strings.NewReader("not a package"), 13, opts); err == nil {
+		t.Errorf("garbage read as a package")
+	}
+}
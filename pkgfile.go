@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"compress/bzip2"
 	"compress/gzip"
+	"context"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -14,7 +15,6 @@ import (
 	"hash"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path"
 	"regexp"
@@ -30,6 +30,21 @@ const (
 	HASH_MD5 = iota
 	HASH_SHA1
 	HASH_SHA256
+	HASH_SHA512
+	HASH_BLAKE2B
+)
+
+// Formats of package files
+const (
+	PACKAGE_FORMAT_DEB = "deb"
+	PACKAGE_FORMAT_IPK = "ipk"
+)
+
+// Types of Debian packages, see the Package-Type field
+const (
+	PACKAGE_TYPE_DEB  = "deb"
+	PACKAGE_TYPE_UDEB = "udeb"
+	PACKAGE_TYPE_DDEB = "ddeb"
 )
 
 type PackageOptions struct {
@@ -37,14 +52,23 @@ type PackageOptions struct {
 	// This is useful for quick scans.
 	MetaOnly bool
 
-	// Set a hash type, one of HASH_MD5, HASH_SHA1 or HASH_SHA256.
-	// Default is HASH_MD5
+	// Set a hash type, one of HASH_MD5, HASH_SHA1, HASH_SHA256, HASH_SHA512
+	// or HASH_BLAKE2B. Default is HASH_MD5
 	Hash int
 
+	// Additional hash types, computed in the same pass as Hash. Checksums of
+	// the package itself are computed for all of them and SHA256, unless
+	// MetaOnly is set.
+	Hashes []int
+
 	// Recalculate checksums, because dpkg is quite lousy here.
 	// Usually it is a very good idea to do so, but not needed if the package
 	// information is not intended to be used for system verification.
+	// Files are not hashed at all otherwise.
 	RecalculateChecksums bool
+
+	// HTTP options of remote packages. DefaultHTTPOptions are used if nil.
+	HTTP *HTTPOptions
 }
 
 var DefaultPackageOptions = &PackageOptions{
@@ -53,17 +77,10 @@ var DefaultPackageOptions = &PackageOptions{
 	RecalculateChecksums: true,
 }
 
-// OpenPackageFile from URI string.
+// OpenPackageFile from URI string. Paths and file, http and https URIs are
+// supported, other schemes can be added with RegisterOpener.
 func OpenPackageFile(uri string, opts *PackageOptions) (*PackageFile, error) {
-	var pf *PackageFile
-	var err error
-	if strings.Contains(uri, "://") && strings.HasPrefix(strings.ToLower(uri), "http") {
-		pf, err = openPackageURL(uri, opts)
-	} else {
-		pf, err = openPackagePath(uri, opts)
-	}
-
-	return pf, err
+	return OpenPackageContext(context.Background(), uri, opts)
 }
 
 func openPackagePath(path string, opts *PackageOptions) (*PackageFile, error) {
@@ -78,7 +95,7 @@ func openPackagePath(path string, opts *PackageOptions) (*PackageFile, error) {
 		return nil, err
 	}
 
-	p, err := NewPackageFileReader(f).SetMetaonly(opts.MetaOnly).SetHash(opts.Hash).Read()
+	p, err := NewPackageFileReader(f).SetOptions(opts).Read()
 	if err != nil {
 		return nil, err
 	}
@@ -87,42 +104,31 @@ func openPackagePath(path string, opts *PackageOptions) (*PackageFile, error) {
 	return p, nil
 }
 
-// openPackageURL reads package info from a HTTP URL
-func openPackageURL(path string, opts *PackageOptions) (*PackageFile, error) {
-	resp, err := http.Get(path)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	p, err := NewPackageFileReader(resp.Body).SetMetaonly(opts.MetaOnly).SetHash(opts.Hash).Read()
-	if err != nil {
-		return nil, err
-	}
-	p.setPath(path).fileSize = uint64(resp.ContentLength)
-	if lm := resp.Header.Get("Last-Modified"); len(lm) > 0 {
-		t, _ := time.Parse(time.RFC1123, lm) // ignore malformed timestamps
-		p.fileTime = t
-	}
-	return p, nil
-}
-
 // PackageFileReader object
 type PackageFileReader struct {
 	reader   io.Reader
 	pkg      *PackageFile
-	arcnt    *ar.Reader
+	member   io.Reader
 	metaonly bool
 	hash     int
+	hashes   []int
+	rehash   bool
+	handler  FileHandler
+	filter   func(header *tar.Header) bool
 }
 
+// FileHandler is called for each entry of the data archive with its tar
+// header and content. Content is empty for anything but regular files.
+// Returning io.EOF stops reading the data archive.
+type FileHandler func(header *tar.Header, content io.Reader) error
+
 // PackageFileReader constructor
 func NewPackageFileReader(reader io.Reader) *PackageFileReader {
 	pfr := new(PackageFileReader)
 	pfr.reader = reader
 	pfr.pkg = NewPackageFile()
-	pfr.arcnt = ar.NewReader(pfr.reader)
 	pfr.metaonly = true
+	pfr.rehash = true
 
 	return pfr
 }
@@ -138,6 +144,42 @@ func (pfr *PackageFileReader) SetHash(hash int) *PackageFileReader {
 	return pfr
 }
 
+// SetHashes sets additional hash types of calculated checksums.
+func (pfr *PackageFileReader) SetHashes(hashes ...int) *PackageFileReader {
+	pfr.hashes = hashes
+	return pfr
+}
+
+// SetRecalculateChecksums turns calculating checksums of files on or off.
+// It is on by default.
+func (pfr *PackageFileReader) SetRecalculateChecksums(rehash bool) *PackageFileReader {
+	pfr.rehash = rehash
+	return pfr
+}
+
+// SetOptions sets meta-only reading, hash types and recalculating of
+// checksums from package options.
+func (pfr *PackageFileReader) SetOptions(opts *PackageOptions) *PackageFileReader {
+	return pfr.SetMetaonly(opts.MetaOnly).SetHash(opts.Hash).SetHashes(opts.Hashes...).SetRecalculateChecksums(opts.RecalculateChecksums)
+}
+
+// SetFileHandler sets a handler receiving the files of the data archive,
+// e.g. to extract them. It is not called if only meta-data is read.
+func (pfr *PackageFileReader) SetFileHandler(handler FileHandler) *PackageFileReader {
+	pfr.handler = handler
+	return pfr
+}
+
+// SetFileFilter restricts the file handler to entries accepted by the filter,
+// which are streamed out of the data archive. Nothing else of the data
+// archive is processed then: files are not hashed, and ELF objects,
+// copyright and checksums of conffiles are not read, nor is the package
+// hashed. This is useful to read a few files of large packages.
+func (pfr *PackageFileReader) SetFileFilter(filter func(header *tar.Header) bool) *PackageFileReader {
+	pfr.filter = filter
+	return pfr
+}
+
 // Error checker
 func (pfr PackageFileReader) checkErr(err error) bool {
 	if err != nil {
@@ -146,31 +188,31 @@ func (pfr PackageFileReader) checkErr(err error) bool {
 	return err == nil
 }
 
-// Decompress Tar data from gz or xz
-func (pfr *PackageFileReader) decompressTar(header ar.Header) *tar.Reader {
-	var gzbuf bytes.Buffer
-	var trbuf bytes.Buffer
-
-	_, cperr := io.Copy(&gzbuf, pfr.arcnt)
-	pfr.checkErr(cperr)
-
-	if strings.HasSuffix(header.Name, ".gz") {
-		pfr.checkErr(pfr.pkg.unGzip(&trbuf, gzbuf.Bytes()))
-	} else if strings.HasSuffix(header.Name, ".xz") {
-		pfr.checkErr(pfr.pkg.unXz(&trbuf, gzbuf.Bytes()))
-	} else if strings.HasSuffix(header.Name, ".bz2") {
-		pfr.checkErr(pfr.pkg.unBzip(&trbuf, gzbuf.Bytes()))
-	} else if strings.HasSuffix(header.Name, ".lzma") {
-		pfr.checkErr(pfr.pkg.unLzma(&trbuf, gzbuf.Bytes()))
+// Decompress Tar data from gz, xz, bz2 or lzma. The member is streamed, and
+// the decompressor must be closed.
+func (pfr *PackageFileReader) decompressTar(header ar.Header) (*tar.Reader, io.Closer) {
+	var r io.ReadCloser = ioutil.NopCloser(pfr.member)
+	var err error
+	switch {
+	case strings.HasSuffix(header.Name, ".gz"):
+		r, err = gzip.NewReader(pfr.member)
+	case strings.HasSuffix(header.Name, ".xz"):
+		var xzread *xz.Reader
+		xzread, err = xz.NewReader(pfr.member, 0)
+		r = ioutil.NopCloser(xzread)
+	case strings.HasSuffix(header.Name, ".bz2"):
+		r = ioutil.NopCloser(bzip2.NewReader(pfr.member))
+	case strings.HasSuffix(header.Name, ".lzma"):
+		r = lzma.NewReader(pfr.member)
 	}
-
-	return tar.NewReader(&trbuf)
+	pfr.checkErr(err)
+	return tar.NewReader(r), r
 }
 
 // Read _gpgbuiler file (self-signed Debian package with no role)
 func (pfr *PackageFileReader) processGpgBuilderFile(header ar.Header) {
 	var buff bytes.Buffer
-	_, err := io.Copy(&buff, pfr.arcnt)
+	_, err := io.Copy(&buff, pfr.member)
 	pfr.checkErr(err)
 	pfr.pkg.gpgbuilder = strings.TrimSpace(buff.String())
 }
@@ -182,21 +224,78 @@ func (pfr *PackageFileReader) processDataFile(header ar.Header) {
 	}
 
 	var databuf bytes.Buffer
-	tarFile := pfr.decompressTar(header)
+	var mh *multiHash
+	pfr.pkg.hash = pfr.hash
+	hashes := append([]int{pfr.hash}, pfr.hashes...)
+	keepMD5 := false
+	for _, ht := range hashes {
+		keepMD5 = keepMD5 || ht == HASH_MD5
+	}
+	if pfr.rehash {
+		// MD5 is always calculated for conffiles
+		mh = newMultiHash(append(hashes, HASH_MD5)...)
+	}
+	tarFile, dec := pfr.decompressTar(header)
+	defer dec.Close()
 	for {
 		hdr, err := tarFile.Next()
 		if err == io.EOF {
 			break
 		}
 
+		pfr.checkErr(err)
 		pfr.pkg.addFileInfo(*hdr)
 
+		if pfr.filter != nil {
+			// Accepted files are streamed, nothing else is processed
+			if pfr.handler != nil && pfr.filter(hdr) {
+				if err = pfr.handler(hdr, tarFile); err == io.EOF {
+					return
+				}
+				pfr.checkErr(err)
+			}
+			continue
+		}
+
+		databuf.Reset()
 		// Calculate checksum of a content payload file
 		if hdr.Typeflag == tar.TypeReg {
-			databuf.Reset()
-			_, err = io.Copy(&databuf, tarFile)
+			cf := pfr.pkg.conffiles.Get(absPath(hdr.Name))
+			fileHash := mh
+			if fileHash == nil && cf != nil {
+				fileHash = newMultiHash(HASH_MD5)
+			}
+			if fileHash != nil {
+				fileHash.Reset()
+				_, err = io.Copy(io.MultiWriter(&databuf, fileHash), tarFile)
+				pfr.checkErr(err)
+				sums := fileHash.Sums()
+				if cf != nil {
+					cf.hash = sums[HASH_MD5]
+				}
+				if mh != nil {
+					if !keepMD5 {
+						delete(sums, HASH_MD5)
+					}
+					pfr.pkg.SetCalculatedChecksum(hdr.Name, sums[pfr.hash])
+					pfr.pkg.fileChecksums[hdr.Name] = sums
+				}
+			} else {
+				_, err = io.Copy(&databuf, tarFile)
+				pfr.checkErr(err)
+			}
+			if pfr.pkg.isCopyrightFile(hdr.Name) {
+				pfr.pkg.parseCopyrightFile(databuf.Bytes())
+			}
+			if elfFile := newElfFile(hdr.Name, databuf.Bytes()); elfFile != nil {
+				pfr.pkg.elfs = append(pfr.pkg.elfs, *elfFile)
+			}
+		}
+		if pfr.handler != nil {
+			if err = pfr.handler(hdr, bytes.NewReader(databuf.Bytes())); err == io.EOF {
+				return
+			}
 			pfr.checkErr(err)
-			pfr.pkg.SetCalculatedChecksum(hdr.Name, NewBytesChecksum(databuf.Bytes()).SetHash(pfr.hash).Sum())
 		}
 	}
 }
@@ -204,7 +303,7 @@ func (pfr *PackageFileReader) processDataFile(header ar.Header) {
 // Read versision of the package managaer
 func (pfr *PackageFileReader) processDebianBinaryFile(header ar.Header) {
 	var buff bytes.Buffer
-	_, err := io.Copy(&buff, pfr.arcnt)
+	_, err := io.Copy(&buff, pfr.member)
 	pfr.checkErr(err)
 	pfr.pkg.debVersion = strings.TrimSpace(buff.String())
 }
@@ -212,7 +311,8 @@ func (pfr *PackageFileReader) processDebianBinaryFile(header ar.Header) {
 // Read control file, compressed with tar and gzip or xz
 func (pfr *PackageFileReader) processControlFile(header ar.Header) {
 	var databuf bytes.Buffer
-	tarFile := pfr.decompressTar(header)
+	tarFile, dec := pfr.decompressTar(header)
+	defer dec.Close()
 	for {
 		hdr, err := tarFile.Next()
 		if err == io.EOF {
@@ -223,7 +323,7 @@ func (pfr *PackageFileReader) processControlFile(header ar.Header) {
 			_, err = io.Copy(&databuf, tarFile)
 			pfr.checkErr(err)
 
-			switch hdr.Name[2:] {
+			switch strings.TrimPrefix(hdr.Name, "./") {
 			case "postinst":
 				pfr.pkg.postinst = databuf.String()
 			case "postrm":
@@ -245,9 +345,9 @@ func (pfr *PackageFileReader) processControlFile(header ar.Header) {
 			case "conffiles":
 				pfr.pkg.parseConffilesFile(databuf.Bytes())
 			case "templates":
-				// If it is needed
+				pfr.pkg.parseTemplatesFile(databuf.Bytes())
 			case "config":
-				// Old packaging style
+				pfr.pkg.config = databuf.String()
 			default:
 				// Log unhandled content and the name here
 			}
@@ -256,35 +356,98 @@ func (pfr *PackageFileReader) processControlFile(header ar.Header) {
 
 }
 
-// Read Debian package data from the stream
-func (pfr *PackageFileReader) Read() (*PackageFile, error) {
-	for {
-		header, err := pfr.arcnt.Next()
-		if err != nil {
-			if err == io.EOF {
-				break
+// Read Debian package data from the stream. Malformed packages are
+// reported as errors.
+func (pfr *PackageFileReader) Read() (pkg *PackageFile, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkg = nil
+			if rerr, ok := r.(error); ok {
+				err = rerr
 			} else {
-				panic(err)
-			}
-		} else {
-			// Yocto's IPK has trailing path for some weird reasons (same format tho)
-			header.Name = path.Base(strings.ReplaceAll(header.Name, "/", ""))
-
-			if strings.HasPrefix(header.Name, "control.") {
-				pfr.processControlFile(*header)
-			} else if strings.HasPrefix(header.Name, "data.") {
-				pfr.processDataFile(*header)
-			} else if header.Name == "_gpgbuilder" {
-				pfr.processGpgBuilderFile(*header)
-			} else if header.Name == "debian-binary" {
-				pfr.processDebianBinaryFile(*header)
+				err = fmt.Errorf("%v", r)
 			}
 		}
+	}()
+
+	var mh *multiHash
+	var src io.Reader = pfr.reader
+	if !pfr.metaonly && pfr.filter == nil {
+		// Hash the package while reading it, it is read completely. SHA256
+		// is always calculated, to verify packages with their index.
+		mh = newMultiHash(append([]int{pfr.hash, HASH_SHA256}, pfr.hashes...)...)
+		src = io.TeeReader(pfr.reader, mh)
 	}
 
+	// Packages are ar archives, but opkg also has them in gzipped tar archives
+	buf := bufio.NewReader(src)
+	if magic, _ := buf.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		pfr.pkg.format = PACKAGE_FORMAT_IPK
+		pfr.readTarContainer(buf)
+	} else {
+		pfr.readArContainer(buf)
+	}
+
+	if mh != nil {
+		_, err := io.Copy(ioutil.Discard, buf)
+		pfr.checkErr(err)
+		pfr.pkg.packageChecksums = mh.Sums()
+	}
 	return pfr.pkg, nil
 }
 
+// Read members of an ar archive
+func (pfr *PackageFileReader) readArContainer(r io.Reader) {
+	arcnt := ar.NewReader(r)
+	for {
+		header, err := arcnt.Next()
+		if err == io.EOF {
+			break
+		}
+		pfr.checkErr(err)
+
+		// Yocto's IPK has trailing path for some weird reasons (same format tho)
+		if strings.HasSuffix(header.Name, "/") {
+			pfr.pkg.format = PACKAGE_FORMAT_IPK
+		}
+		pfr.processMember(path.Base(strings.ReplaceAll(header.Name, "/", "")), arcnt)
+	}
+}
+
+// Read members of a gzipped tar archive of opkg
+func (pfr *PackageFileReader) readTarContainer(r io.Reader) {
+	gzread, err := gzip.NewReader(r)
+	pfr.checkErr(err)
+	defer gzread.Close()
+
+	tarFile := tar.NewReader(gzread)
+	for {
+		hdr, err := tarFile.Next()
+		if err == io.EOF {
+			break
+		}
+		pfr.checkErr(err)
+		if hdr.Typeflag == tar.TypeReg {
+			pfr.processMember(path.Base(hdr.Name), tarFile)
+		}
+	}
+}
+
+// Process a member of the package archive by its name
+func (pfr *PackageFileReader) processMember(name string, member io.Reader) {
+	pfr.member = member
+	header := ar.Header{Name: name}
+	if strings.HasPrefix(name, "control.") {
+		pfr.processControlFile(header)
+	} else if strings.HasPrefix(name, "data.") {
+		pfr.processDataFile(header)
+	} else if name == "_gpgbuilder" {
+		pfr.processGpgBuilderFile(header)
+	} else if name == "debian-binary" {
+		pfr.processDebianBinaryFile(header)
+	}
+}
+
 // Checksum object computes and returns the SHA256, SHA1 and MD5 checksums
 // encoded in hexadecimal) of the package file.
 //
@@ -311,7 +474,7 @@ func NewBytesChecksum(data []byte) *Checksum {
 
 func (cs *Checksum) SetHash(hash int) *Checksum {
 	switch hash {
-	case HASH_MD5, HASH_SHA1, HASH_SHA256:
+	case HASH_MD5, HASH_SHA1, HASH_SHA256, HASH_SHA512, HASH_BLAKE2B:
 		cs.hash = hash
 	default:
 		panic(fmt.Sprintf("Unknown hash: %d", hash))
@@ -361,6 +524,24 @@ func (cs *Checksum) SHA1() string {
 	return sum
 }
 
+// SHA512 checksum
+func (cs *Checksum) SHA512() string {
+	sum, err := cs.compute(newHash(HASH_SHA512))
+	if err != nil {
+		panic(err)
+	}
+	return sum
+}
+
+// BLAKE2b checksum, 512 bits long
+func (cs *Checksum) BLAKE2b() string {
+	sum, err := cs.compute(newHash(HASH_BLAKE2B))
+	if err != nil {
+		panic(err)
+	}
+	return sum
+}
+
 // MD5 checksum
 func (cs *Checksum) MD5() string {
 	sum, err := cs.compute(md5.New())
@@ -378,6 +559,10 @@ func (cs *Checksum) Sum() string {
 		return cs.SHA1()
 	case HASH_SHA256:
 		return cs.SHA256()
+	case HASH_SHA512:
+		return cs.SHA512()
+	case HASH_BLAKE2B:
+		return cs.BLAKE2b()
 	}
 	return cs.MD5()
 }
@@ -385,6 +570,8 @@ func (cs *Checksum) Sum() string {
 // PackageFile object
 type PackageFile struct {
 	path       string
+	format     string
+	pkgType    string
 	fileSize   uint64
 	fileTime   time.Time
 	debVersion string
@@ -393,6 +580,7 @@ type PackageFile struct {
 	prerm    string
 	postinst string
 	postrm   string
+	config   string
 
 	checksum   *Checksum
 	control    *ControlFile
@@ -400,24 +588,36 @@ type PackageFile struct {
 	shlibs     *SharedLibsFile
 	triggers   *TriggerFile
 	conffiles  *CfgFilesFile
+	copyright  *CopyrightFile
+	templates  *TemplatesFile
 	gpgbuilder string
 
 	files                   []FileInfo
+	elfs                    []ElfFile
+	hash                    int
 	fileMd5Checksums        map[string]string
 	fileCalculatedChecksums map[string]string
+	fileChecksums           map[string]map[int]string
+	packageChecksums        map[int]string
 }
 
 // Constructor
 func NewPackageFile() *PackageFile {
 	pf := new(PackageFile)
+	pf.format = PACKAGE_FORMAT_DEB
 	pf.fileMd5Checksums = make(map[string]string)    // Original dpkg's md5sums. They are always missing configs.
 	pf.fileCalculatedChecksums = map[string]string{} // SHA calculated checksums. Parsing package is slower, if this is on.
+	pf.fileChecksums = make(map[string]map[int]string)
+	pf.packageChecksums = make(map[int]string)
 	pf.files = make([]FileInfo, 0)
+	pf.elfs = make([]ElfFile, 0)
 	pf.control = NewControlFile()
 	pf.symbols = NewSymbolsFile()
 	pf.shlibs = NewSharedLibsFile()
 	pf.triggers = NewTriggerFile()
 	pf.conffiles = NewCfgFilesFiles()
+	pf.copyright = NewCopyrightFile()
+	pf.templates = NewTemplatesFile()
 
 	return pf
 }
@@ -430,59 +630,6 @@ func (c *PackageFile) setPath(path string) *PackageFile {
 	return c
 }
 
-// unBz2 decompresses Bzip data array
-func (c *PackageFile) unLzma(writer io.Writer, data []byte) error {
-	lzmaread := lzma.NewReader(bytes.NewBuffer(data))
-	defer lzmaread.Close()
-	data, err := ioutil.ReadAll(lzmaread)
-	if err == nil {
-		_, err = writer.Write(data)
-	}
-	return err
-}
-
-// unBz2 decompresses Bzip data array
-func (c *PackageFile) unBzip(writer io.Writer, data []byte) error {
-	bzread := bzip2.NewReader(bytes.NewBuffer(data))
-	data, err := ioutil.ReadAll(bzread)
-	if err == nil {
-		_, err = writer.Write(data)
-	}
-	return err
-}
-
-// unXz decompresses Lempel-Ziv-Markow data
-func (c *PackageFile) unXz(writer io.Writer, data []byte) error {
-	xzread, err := xz.NewReader(bytes.NewBuffer(data), 0)
-	if err != nil {
-		panic(err)
-	}
-
-	data, err = ioutil.ReadAll(xzread)
-	if err == nil {
-		_, err = writer.Write(data)
-	}
-
-	return err
-}
-
-// unGzip decompresses compressed Gzip data array
-func (c *PackageFile) unGzip(writer io.Writer, data []byte) error {
-	gzread, err := gzip.NewReader(bytes.NewBuffer(data))
-	if err != nil {
-		panic(err)
-	}
-
-	defer gzread.Close()
-
-	data, err = ioutil.ReadAll(gzread)
-	if err == nil {
-		writer.Write(data)
-	}
-
-	return err
-}
-
 // Parse MD5 checksums file
 func (c *PackageFile) parseMd5Sums(data []byte) {
 	var sfx = regexp.MustCompile(`\s+|\t+`)
@@ -500,6 +647,7 @@ func (c *PackageFile) addFileInfo(header tar.Header) {
 	info := new(FileInfo)
 	info.name = header.Name
 	info.mode = header.FileInfo().Mode()
+	info.isDir = header.Typeflag == tar.TypeDir
 	info.size = header.Size
 	info.modTime = header.ModTime
 	info.owner = header.Uname
@@ -519,6 +667,11 @@ func (c *PackageFile) parseTriggersFile(data []byte) {
 	c.triggers.parse(data)
 }
 
+// Parse debconf templates
+func (c *PackageFile) parseTemplatesFile(data []byte) {
+	c.templates.parse(data)
+}
+
 // Parse symbols
 func (c *PackageFile) parseSymbolsFile(data []byte) {
 	c.symbols.parse(data)
@@ -529,6 +682,84 @@ func (c *PackageFile) parseSharedLibsFile(data []byte) {
 	c.shlibs.parse(data)
 }
 
+// Convert a path in the data archive, such as "./etc/foo/", to an absolute one
+func absPath(name string) string {
+	return "/" + strings.Trim(strings.TrimPrefix(name, "."), "/")
+}
+
+// StreamPackageFile streams a file of the data archive of a local package,
+// e.g. "/usr/bin/hello", to the handler without reading it into memory.
+// Hard and symbolic links within the package are followed, and the header
+// is of the file linked to. Missing files are reported as os.ErrNotExist.
+func StreamPackageFile(pkgPath string, name string, handler FileHandler) error {
+	name = absPath(name)
+	for links := 0; links < 8; links++ {
+		f, err := os.Open(pkgPath)
+		if err != nil {
+			return err
+		}
+		var found *tar.Header
+		_, err = NewPackageFileReader(f).SetMetaonly(false).SetRecalculateChecksums(false).
+			SetFileFilter(func(hdr *tar.Header) bool { return absPath(hdr.Name) == name }).
+			SetFileHandler(func(hdr *tar.Header, content io.Reader) error {
+				found = hdr
+				if hdr.Typeflag == tar.TypeReg {
+					if err := handler(hdr, content); err != nil {
+						return err
+					}
+				}
+				return io.EOF
+			}).Read()
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case found == nil:
+			return &os.PathError{Op: "read", Path: name, Err: os.ErrNotExist}
+		case found.Typeflag == tar.TypeLink:
+			name = absPath(found.Linkname)
+		case found.Typeflag == tar.TypeSymlink && path.IsAbs(found.Linkname):
+			name = path.Clean(found.Linkname)
+		case found.Typeflag == tar.TypeSymlink:
+			name = path.Join(path.Dir(name), found.Linkname)
+		case found.Typeflag == tar.TypeReg:
+			return nil
+		default:
+			return fmt.Errorf("%s: not a regular file", name)
+		}
+	}
+	return fmt.Errorf("%s: too many levels of links", name)
+}
+
+// ReadPackageFile reads the content of a file of the data archive of a local
+// package, see StreamPackageFile.
+func ReadPackageFile(pkgPath string, name string) ([]byte, error) {
+	var content []byte
+	err := StreamPackageFile(pkgPath, name, func(hdr *tar.Header, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		content = data
+		return err
+	})
+	return content, err
+}
+
+// Check if the path in data archive is the copyright file of the package
+func (c *PackageFile) isCopyrightFile(name string) bool {
+	name = absPath(name)
+	if c.control.Package() != "" {
+		return name == path.Join("/usr/share/doc", c.control.Package(), "copyright")
+	}
+	matched, _ := path.Match("/usr/share/doc/*/copyright", name)
+	return matched && c.copyright.Text() == ""
+}
+
+// Parse copyright file. Malformed machine-readable files are kept as text.
+func (c *PackageFile) parseCopyrightFile(data []byte) {
+	c.copyright.parse(data)
+}
+
 // Parse control file
 func (c *PackageFile) parseControlFile(data []byte) {
 	var line string
@@ -587,6 +818,12 @@ func (c *PackageFile) PostUninstallScript() string {
 	return c.postrm
 }
 
+// ConfigScript returns the debconf config script, which asks the questions
+// before the package is configured.
+func (c *PackageFile) ConfigScript() string {
+	return c.config
+}
+
 // GetFileMd5Sums returns file checksum by relative path from the md5sums file.
 // NOTE: md5sums file omits configuration files.
 func (c *PackageFile) GetFileMd5Sums(path string) string {
@@ -628,11 +865,33 @@ func (c *PackageFile) ConffilesFile() *CfgFilesFile {
 	return c.conffiles
 }
 
+// CopyrightFile returns the package copyright file, found in the data archive.
+// It is empty if files were not processed.
+func (c *PackageFile) CopyrightFile() *CopyrightFile {
+	return c.copyright
+}
+
+// TemplatesFile returns parsed debconf templates.
+func (c *PackageFile) TemplatesFile() *TemplatesFile {
+	return c.templates
+}
+
 // Return meta-content of the package
 func (c *PackageFile) Files() []FileInfo {
 	return c.files
 }
 
+// ElfFiles returns dynamic linking information of ELF objects in the package.
+func (c *PackageFile) ElfFiles() []ElfFile {
+	return c.elfs
+}
+
+// Format returns the format of the package file, PACKAGE_FORMAT_DEB or
+// PACKAGE_FORMAT_IPK.
+func (c *PackageFile) Format() string {
+	return c.format
+}
+
 // DpkgVersion returns the version of the format of the .deb file
 func (c *PackageFile) DebVersion() string {
 	return c.debVersion
@@ -653,3 +912,36 @@ func (c *PackageFile) SetCalculatedChecksum(path, sum string) *PackageFile {
 func (c *PackageFile) GetCalculatedChecksum(path string) string {
 	return c.fileCalculatedChecksums[path]
 }
+
+// GetCalculatedChecksums returns calculated checksums of a file of all hash
+// types, by hash type.
+func (c *PackageFile) GetCalculatedChecksums(path string) map[int]string {
+	return c.fileChecksums[path]
+}
+
+// PackageChecksums returns checksums of the package itself by hash type,
+// calculated while reading it. It is empty if only meta-data was read.
+func (c *PackageFile) PackageChecksums() map[int]string {
+	return c.packageChecksums
+}
+
+// CalculatedChecksumHash returns the hash type of calculated checksums,
+// e.g. HASH_SHA256.
+func (c *PackageFile) CalculatedChecksumHash() int {
+	return c.hash
+}
+
+// HashName returns the name of a hash type, e.g. "SHA256" for HASH_SHA256.
+func HashName(hash int) string {
+	switch hash {
+	case HASH_SHA1:
+		return "SHA1"
+	case HASH_SHA256:
+		return "SHA256"
+	case HASH_SHA512:
+		return "SHA512"
+	case HASH_BLAKE2B:
+		return "BLAKE2b-512"
+	}
+	return "MD5"
+}
@@ -0,0 +1,103 @@
+package deb
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Directory of detached debug symbols, looked up by GNU build ID
+const DEBUG_BUILD_ID_DIR = "/usr/lib/debug/.build-id"
+
+// PackageType returns the Package-Type field, e.g. "udeb", or an empty string.
+func (cf *ControlFile) PackageType() string {
+	return strings.ToLower(cf.Field("Package-Type"))
+}
+
+// InstallerMenuItem returns the position of a udeb in the menu of the
+// Debian installer, or 0 if it has no menu item.
+func (cf *ControlFile) InstallerMenuItem() int {
+	item, _ := strconv.Atoi(cf.Field("Installer-Menu-Item"))
+	return item
+}
+
+// Subarchitecture returns the subarchitectures a udeb is restricted to.
+func (cf *ControlFile) Subarchitecture() []string {
+	return strings.Fields(cf.Field("Subarchitecture"))
+}
+
+// KernelVersion returns the version of the kernel a udeb of modules is built for.
+func (cf *ControlFile) KernelVersion() string {
+	return cf.Field("Kernel-Version")
+}
+
+// BuildIds returns GNU build IDs of the objects a debug symbols package
+// has symbols for, lowercase hex.
+func (cf *ControlFile) BuildIds() []string {
+	return strings.Fields(strings.ToLower(cf.Field("Build-Ids")))
+}
+
+// BuildIdDebugPath returns the path of the detached debug symbols of a
+// build ID, e.g. "/usr/lib/debug/.build-id/ab/cdef.debug".
+func BuildIdDebugPath(id string) (string, error) {
+	id = strings.ToLower(id)
+	if len(id) < 3 || strings.Trim(id, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("invalid build ID '%s'", id)
+	}
+	return path.Join(DEBUG_BUILD_ID_DIR, id[:2], id[2:]+".debug"), nil
+}
+
+// PackageType returns the type of the package, PACKAGE_TYPE_DEB,
+// PACKAGE_TYPE_UDEB or PACKAGE_TYPE_DDEB. It is the Package-Type field, or
+// the type of the file name, or ddeb for automatic dbgsym packages.
+func (c *PackageFile) PackageType() string {
+	cf := c.ControlFile()
+	switch {
+	case cf.PackageType() != "":
+		return cf.PackageType()
+	case c.pkgType != "":
+		return c.pkgType
+	case cf.Field("Auto-Built-Package") == "debug-symbols" || strings.HasSuffix(cf.Package(), "-dbgsym"):
+		return PACKAGE_TYPE_DDEB
+	}
+	return PACKAGE_TYPE_DEB
+}
+
+// DebugFiles maps build IDs of a debug symbols package to paths of their
+// debug symbols. If files of the package were read, only shipped paths are
+// returned.
+func (c *PackageFile) DebugFiles() map[string]string {
+	files := make(map[string]string)
+	shipped := make(map[string]bool)
+	for _, fi := range c.files {
+		shipped[absPath(fi.Name())] = true
+	}
+	for _, id := range c.ControlFile().BuildIds() {
+		debug, err := BuildIdDebugPath(id)
+		if err == nil && (len(shipped) == 0 || shipped[debug]) {
+			files[id] = debug
+		}
+	}
+	return files
+}
+
+// DebugBuildIds returns build IDs of the debug symbols a package ships in
+// DEBUG_BUILD_ID_DIR, whether they are listed in Build-Ids or not.
+func (c *PackageFile) DebugBuildIds() []string {
+	ids := make([]string, 0)
+	for _, fi := range c.files {
+		name := absPath(fi.Name())
+		if !fi.Mode().IsRegular() || !strings.HasPrefix(name, DEBUG_BUILD_ID_DIR+"/") || !strings.HasSuffix(name, ".debug") {
+			continue
+		}
+		dir, file := path.Split(strings.TrimPrefix(name, DEBUG_BUILD_ID_DIR+"/"))
+		id := strings.TrimSuffix(dir, "/") + strings.TrimSuffix(file, ".debug")
+		if _, err := BuildIdDebugPath(id); err == nil && len(dir) == 3 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
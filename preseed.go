@@ -0,0 +1,181 @@
+package deb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PreseedEntry is an answer to a debconf question in a preseed file,
+// as accepted by debconf-set-selections:
+//
+//	owner question type value
+type PreseedEntry struct {
+	owner    string
+	question string
+	qtype    string
+	value    string
+	line     int
+}
+
+// Owner returns the package owning the question.
+func (pe *PreseedEntry) Owner() string {
+	return pe.owner
+}
+
+// Question returns the question (template) name.
+func (pe *PreseedEntry) Question() string {
+	return pe.question
+}
+
+// Type returns the question type, or "seen" for lines setting the seen flag.
+func (pe *PreseedEntry) Type() string {
+	return pe.qtype
+}
+
+// Value returns the answer.
+func (pe *PreseedEntry) Value() string {
+	return pe.value
+}
+
+// Line returns the line number the entry starts at.
+func (pe *PreseedEntry) Line() int {
+	return pe.line
+}
+
+// ParsePreseed reads preseed entries. Lines ending with a backslash are
+// continued on the next line.
+func ParsePreseed(r io.Reader) ([]PreseedEntry, error) {
+	entries := make([]PreseedEntry, 0)
+	scn := bufio.NewScanner(r)
+	lineno, start := 0, 0
+	var line string
+	for scn.Scan() {
+		lineno++
+		if line == "" {
+			start = lineno
+		}
+		text := strings.TrimRight(scn.Text(), " \t\r")
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\")
+			continue
+		}
+		line += text
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			fields := strings.Fields(trimmed)
+			if len(fields) < 3 {
+				return nil, &ParseError{File: "preseed", Line: start, Err: "expected 'owner question type value'"}
+			}
+			pe := PreseedEntry{owner: fields[0], question: fields[1], qtype: fields[2], line: start}
+			if len(fields) > 3 {
+				// Value is the rest of the line after the type, with its inner spacing kept
+				rest := trimmed
+				for _, field := range fields[:3] {
+					rest = strings.TrimLeft(rest[len(field):], " \t")
+				}
+				pe.value = rest
+			}
+			entries = append(entries, pe)
+		}
+		line = ""
+	}
+	return entries, scn.Err()
+}
+
+// Collect templates of packages by question name. The first package wins.
+func preseedTemplates(pkgs []*PackageFile) map[string]*DebconfTemplate {
+	templates := make(map[string]*DebconfTemplate)
+	for _, pf := range pkgs {
+		for idx := range pf.TemplatesFile().Templates() {
+			dt := &pf.TemplatesFile().Templates()[idx]
+			if _, ok := templates[dt.Name()]; !ok {
+				templates[dt.Name()] = dt
+			}
+		}
+	}
+	return templates
+}
+
+// ValidatePreseed checks preseed entries against debconf templates shipped
+// in the packages: questions must exist, types must match, booleans must be
+// "true" or "false" and select or multiselect values must be among the choices.
+func ValidatePreseed(entries []PreseedEntry, pkgs []*PackageFile) []error {
+	errs := make([]error, 0)
+	templates := preseedTemplates(pkgs)
+	for _, pe := range entries {
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, &ParseError{File: "preseed", Line: pe.line, Err: fmt.Sprintf(format, args...)})
+		}
+
+		dt, ok := templates[pe.question]
+		if !ok {
+			fail("unknown question '%s'", pe.question)
+			continue
+		}
+		if pe.qtype == "seen" {
+			if pe.value != "true" && pe.value != "false" {
+				fail("seen flag of '%s' must be true or false", pe.question)
+			}
+			continue
+		}
+		if pe.qtype != dt.Type() {
+			fail("question '%s' is of type %s, not %s", pe.question, dt.Type(), pe.qtype)
+			continue
+		}
+
+		switch dt.Type() {
+		case "boolean":
+			if pe.value != "true" && pe.value != "false" {
+				fail("value of boolean question '%s' must be true or false", pe.question)
+			}
+		case "select":
+			if !in(pe.value, dt.Values()) {
+				fail("value '%s' of question '%s' is not one of: %s", pe.value, pe.question, strings.Join(dt.Values(), ", "))
+			}
+		case "multiselect":
+			// Values are separated as choices, with commas escaped by a backslash
+			for _, value := range dt.splitChoices(pe.value) {
+				if value != "" && !in(value, dt.Values()) {
+					fail("value '%s' of question '%s' is not one of: %s", value, pe.question, strings.Join(dt.Values(), ", "))
+				}
+			}
+		case "note", "text", "error", "title":
+			fail("question '%s' of type %s can not be preseeded", pe.question, dt.Type())
+		}
+	}
+	return errs
+}
+
+// GeneratePreseed writes a preseed file skeleton answering all questions of
+// the packages with their default values.
+func GeneratePreseed(w io.Writer, pkgs []*PackageFile) error {
+	var buf bytes.Buffer
+	for _, pf := range pkgs {
+		templates := pf.TemplatesFile().Templates()
+		if len(templates) == 0 {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(fmt.Sprintf("### %s\n", pf.ControlFile().Package()))
+		for _, dt := range templates {
+			if !dt.IsQuestion() {
+				continue
+			}
+			buf.WriteString(fmt.Sprintf("# %s\n", dt.Description()))
+			if len(dt.Values()) > 0 {
+				buf.WriteString(fmt.Sprintf("# Choices: %s\n", strings.Join(dt.Values(), ", ")))
+			}
+			value := dt.Default()
+			if dt.Type() == "password" {
+				value = ""
+			}
+			buf.WriteString(strings.TrimRight(fmt.Sprintf("%s %s %s %s", pf.ControlFile().Package(), dt.Name(), dt.Type(), value), " ") + "\n")
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,49 @@
+package deb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Templates of a multiselect question with a choice containing a comma
+const testTemplates = `Template: hello/greetings
+Type: multiselect
+Choices: hello, hello\, world, goodbye
+Description: Greetings to print:
+`
+
+func TestValidatePreseedMultiselect(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBuilder().SetControlFile("templates", testTemplates).WriteDeb(&buf); err != nil {
+		t.Fatal(err)
+	}
+	pf, err := NewPackageFileReader(bytes.NewReader(buf.Bytes())).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"single choice", "hello", true},
+		{"choice with an escaped comma", `hello\, world`, true},
+		{"several choices", `goodbye, hello\, world, hello`, true},
+		{"nothing selected", "", true},
+		{"unescaped comma", "hello, world", false},
+		{"unknown choice", "hello, welcome", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ParsePreseed(strings.NewReader("hello hello/greetings multiselect " + tt.value + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if errs := ValidatePreseed(entries, []*PackageFile{pf}); (len(errs) == 0) != tt.valid {
+				t.Errorf("value %q: errors %v", tt.value, errs)
+			}
+		})
+	}
+}
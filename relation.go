@@ -0,0 +1,207 @@
+package deb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relation is a single package relation, as used in Depends and other
+// relationship fields:
+//
+//	name[:archqual] [(operator version)] [[arch ...]] [<profile ...>]
+type Relation struct {
+	name          string
+	archQualifier string
+	operator      string
+	version       string
+	architectures []string
+	profiles      []string
+}
+
+// NewRelation constructor
+func NewRelation(name string, operator string, version string) *Relation {
+	rel := new(Relation)
+	rel.name, rel.operator, rel.version = name, operator, version
+	return rel
+}
+
+// Name of the related package
+func (rel *Relation) Name() string {
+	return rel.name
+}
+
+// ArchQualifier returns the architecture qualifier after the colon, e.g. "any".
+func (rel *Relation) ArchQualifier() string {
+	return rel.archQualifier
+}
+
+// Operator returns the version relation operator, one of "<<", "<=", "=", ">=", ">>".
+// It is empty for unversioned relations.
+func (rel *Relation) Operator() string {
+	return rel.operator
+}
+
+// Version returns the version the relation refers to.
+func (rel *Relation) Version() string {
+	return rel.version
+}
+
+// Architectures returns the architecture restriction list.
+func (rel *Relation) Architectures() []string {
+	return rel.architectures
+}
+
+// Profiles returns build profile restriction formulas, one per <...> group.
+func (rel *Relation) Profiles() []string {
+	return rel.profiles
+}
+
+// SatisfiedBy returns true if a package of the given version satisfies
+// the version constraint of the relation.
+func (rel *Relation) SatisfiedBy(version string) bool {
+	if rel.operator == "" {
+		return true
+	}
+	cmp := CompareVersions(version, rel.version)
+	switch rel.operator {
+	case "<<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "=":
+		return cmp == 0
+	case ">=":
+		return cmp >= 0
+	case ">>":
+		return cmp > 0
+	}
+	return false
+}
+
+func (rel *Relation) String() string {
+	out := rel.name
+	if rel.archQualifier != "" {
+		out += ":" + rel.archQualifier
+	}
+	if rel.operator != "" {
+		out += " (" + rel.operator + " " + rel.version + ")"
+	}
+	if len(rel.architectures) > 0 {
+		out += " [" + strings.Join(rel.architectures, " ") + "]"
+	}
+	for _, profile := range rel.profiles {
+		out += " <" + profile + ">"
+	}
+	return out
+}
+
+// Parse a single relation
+func (rel *Relation) parse(data string) error {
+	data = strings.TrimSpace(data)
+	end := strings.IndexAny(data, " \t([<")
+	if end < 0 {
+		end = len(data)
+	}
+	rel.name, data = data[:end], strings.TrimSpace(data[end:])
+	if idx := strings.Index(rel.name, ":"); idx > -1 {
+		rel.name, rel.archQualifier = rel.name[:idx], rel.name[idx+1:]
+	}
+	if rel.name == "" {
+		return fmt.Errorf("missing package name")
+	}
+
+	for data != "" {
+		var closing string
+		switch data[0] {
+		case '(':
+			closing = ")"
+		case '[':
+			closing = "]"
+		case '<':
+			closing = ">"
+		default:
+			return fmt.Errorf("unexpected '%s' in relation of '%s'", data, rel.name)
+		}
+		end = strings.Index(data, closing)
+		if end < 0 {
+			return fmt.Errorf("unterminated '%c' in relation of '%s'", data[0], rel.name)
+		}
+		value := strings.TrimSpace(data[1:end])
+		switch data[0] {
+		case '(':
+			if err := rel.parseVersion(value); err != nil {
+				return err
+			}
+		case '[':
+			rel.architectures = strings.Fields(value)
+		case '<':
+			rel.profiles = append(rel.profiles, value)
+		}
+		data = strings.TrimSpace(data[end+1:])
+	}
+
+	return nil
+}
+
+// Parse version constraint without parentheses
+func (rel *Relation) parseVersion(data string) error {
+	end := 0
+	for end < len(data) && strings.ContainsRune("<=>", rune(data[end])) {
+		end++
+	}
+	op := data[:end]
+	rel.version = strings.TrimSpace(data[end:])
+	switch op {
+	case "<", "<=":
+		rel.operator = "<="
+	case ">", ">=":
+		rel.operator = ">="
+	case "<<", "=", ">>":
+		rel.operator = op
+	default:
+		return fmt.Errorf("invalid relation operator '%s' of '%s'", op, rel.name)
+	}
+	if rel.version == "" {
+		return fmt.Errorf("missing version in relation of '%s'", rel.name)
+	}
+	return nil
+}
+
+// RelationGroup is a list of alternative relations, separated with "|".
+type RelationGroup struct {
+	alternatives []Relation
+}
+
+// Alternatives returns relations of the group. Any of them satisfies the group.
+func (grp *RelationGroup) Alternatives() []Relation {
+	return grp.alternatives
+}
+
+func (grp *RelationGroup) String() string {
+	alts := make([]string, 0, len(grp.alternatives))
+	for idx := range grp.alternatives {
+		alts = append(alts, grp.alternatives[idx].String())
+	}
+	return strings.Join(alts, " | ")
+}
+
+// ParseRelations parses the value of a relationship field, such as Depends,
+// into groups of alternatives. All groups must be satisfied.
+func ParseRelations(data string) ([]RelationGroup, error) {
+	groups := make([]RelationGroup, 0)
+	for _, group := range strings.Split(data, ",") {
+		if strings.TrimSpace(group) == "" {
+			continue
+		}
+		grp := RelationGroup{alternatives: make([]Relation, 0)}
+		for _, alt := range strings.Split(group, "|") {
+			rel := new(Relation)
+			if err := rel.parse(alt); err != nil {
+				return nil, err
+			}
+			grp.alternatives = append(grp.alternatives, *rel)
+		}
+		groups = append(groups, grp)
+	}
+	return groups, nil
+}
@@ -0,0 +1,272 @@
+package deb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts of dates of Release files, e.g. "Sat, 19 Oct 2026 08:13:41 UTC"
+var releaseDateLayouts = []string{time.RFC1123, time.RFC1123Z, "Mon, 2 Jan 2006 15:04:05 MST", "Mon, 2 Jan 2006 15:04:05 -0700"}
+
+// Checksum fields of a Release file, in order of appearance
+var releaseHashFields = []string{"MD5Sum", "SHA1", "SHA256", "SHA512"}
+
+// Hash types of the checksum fields
+var releaseHashTypes = map[string]int{"MD5Sum": HASH_MD5, "SHA1": HASH_SHA1, "SHA256": HASH_SHA256, "SHA512": HASH_SHA512}
+
+// Checksum fields trusted to verify index files, as by APT
+var releaseStrongHashFields = []string{"SHA256", "SHA512"}
+
+// ReleaseChecksum is a checksum of an index file listed in a Release file.
+type ReleaseChecksum struct {
+	path string
+	size int64
+	sum  string
+}
+
+// Path of the index file relative to the directory of the Release file
+func (rc *ReleaseChecksum) Path() string {
+	return rc.path
+}
+
+// Size of the index file in bytes
+func (rc *ReleaseChecksum) Size() int64 {
+	return rc.size
+}
+
+// Sum returns the hexadecimal checksum
+func (rc *ReleaseChecksum) Sum() string {
+	return rc.sum
+}
+
+// ReleaseFile is the Release file of a repository suite, describing it and
+// listing checksums of its index files.
+type ReleaseFile struct {
+	fields    *Paragraph
+	checksums map[string][]ReleaseChecksum
+}
+
+// NewReleaseFile constructor
+func NewReleaseFile() *ReleaseFile {
+	rf := new(ReleaseFile)
+	rf.fields = NewParagraph()
+	rf.checksums = make(map[string][]ReleaseChecksum)
+	return rf
+}
+
+// ParseReleaseFile parses a Release file. The signature of an InRelease
+// file must be removed before.
+func ParseReleaseFile(data []byte) (*ReleaseFile, error) {
+	paragraphs, err := parseParagraphs(data)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			perr.File = "Release"
+		}
+		return nil, err
+	}
+	if len(paragraphs) != 1 {
+		return nil, fmt.Errorf("Release file must have exactly one paragraph, found %d", len(paragraphs))
+	}
+
+	rf := NewReleaseFile()
+	for _, name := range paragraphs[0].Names() {
+		value := paragraphs[0].Get(name)
+		isHash := false
+		for _, hf := range releaseHashFields {
+			if strings.EqualFold(hf, name) {
+				name, isHash = hf, true
+			}
+		}
+		if !isHash {
+			rf.fields.Set(name, value)
+			continue
+		}
+		for _, line := range strings.Split(value, "\n") {
+			parts := strings.Fields(line)
+			if len(parts) == 0 {
+				continue
+			}
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("malformed %s entry '%s'", name, line)
+			}
+			size, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed size in %s entry '%s'", name, line)
+			}
+			rf.AddChecksum(name, parts[2], size, parts[0])
+		}
+	}
+	return rf, nil
+}
+
+// Field returns the value of a field, e.g. "Suite" or "Date".
+func (rf *ReleaseFile) Field(name string) string {
+	return rf.fields.Get(name)
+}
+
+// SetField sets the value of a field.
+func (rf *ReleaseFile) SetField(name string, value string) *ReleaseFile {
+	rf.fields.Set(name, value)
+	return rf
+}
+
+// Suite returns the suite name, e.g. "stable".
+func (rf *ReleaseFile) Suite() string {
+	return rf.fields.Get("Suite")
+}
+
+// Codename returns the codename of the suite, e.g. "bookworm".
+func (rf *ReleaseFile) Codename() string {
+	return rf.fields.Get("Codename")
+}
+
+// Architectures returns architectures of the suite.
+func (rf *ReleaseFile) Architectures() []string {
+	return strings.Fields(rf.fields.Get("Architectures"))
+}
+
+// Components returns components of the suite.
+func (rf *ReleaseFile) Components() []string {
+	return strings.Fields(rf.fields.Get("Components"))
+}
+
+// Date returns the time the Release file was created, or the zero time if
+// it has no Date field.
+func (rf *ReleaseFile) Date() (time.Time, error) {
+	return rf.time("Date")
+}
+
+// ValidUntil returns the time the Release file expires, or the zero time if
+// it has no Valid-Until field.
+func (rf *ReleaseFile) ValidUntil() (time.Time, error) {
+	return rf.time("Valid-Until")
+}
+
+// Parse a date field
+func (rf *ReleaseFile) time(name string) (time.Time, error) {
+	value := strings.TrimSpace(rf.fields.Get(name))
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range releaseDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("malformed %s '%s'", name, value)
+}
+
+// CheckValid checks that the Release file is not expired at a time: it is
+// before Valid-Until, and if maxAge is not zero, the Date is not older.
+func (rf *ReleaseFile) CheckValid(now time.Time, maxAge time.Duration) error {
+	validUntil, err := rf.ValidUntil()
+	if err != nil {
+		return err
+	}
+	if !validUntil.IsZero() && now.After(validUntil) {
+		return fmt.Errorf("Release file expired on %s", validUntil.Format(time.RFC1123))
+	}
+	if maxAge == 0 {
+		return nil
+	}
+	date, err := rf.Date()
+	if err != nil {
+		return err
+	}
+	if date.IsZero() {
+		return fmt.Errorf("Release file has no Date to check its age")
+	}
+	if now.Sub(date) > maxAge {
+		return fmt.Errorf("Release file of %s is older than %s", date.Format(time.RFC1123), maxAge)
+	}
+	return nil
+}
+
+// AddChecksum adds a checksum of an index file. The hash field is one of
+// "MD5Sum", "SHA1", "SHA256" or "SHA512".
+func (rf *ReleaseFile) AddChecksum(field string, path string, size int64, sum string) *ReleaseFile {
+	rf.checksums[field] = append(rf.checksums[field], ReleaseChecksum{path: path, size: size, sum: sum})
+	return rf
+}
+
+// Checksums returns checksums of a hash field, sorted by path.
+func (rf *ReleaseFile) Checksums(field string) []ReleaseChecksum {
+	sums := append([]ReleaseChecksum{}, rf.checksums[field]...)
+	sort.SliceStable(sums, func(i, j int) bool { return sums[i].path < sums[j].path })
+	return sums
+}
+
+// Checksum returns the checksum of an index file by its path, using the
+// strongest available hash, and the name of the hash field. Nil is returned
+// if the file is not listed.
+func (rf *ReleaseFile) Checksum(path string) (*ReleaseChecksum, string) {
+	for idx := len(releaseHashFields) - 1; idx >= 0; idx-- {
+		field := releaseHashFields[idx]
+		for _, rc := range rf.checksums[field] {
+			if rc.path == path {
+				rc := rc
+				return &rc, field
+			}
+		}
+	}
+	return nil, ""
+}
+
+// VerifyFile checks size and the strongest checksum of an index file listed
+// in the Release file, given its path, e.g. "main/binary-amd64/Packages.xz".
+// The file must be listed with SHA256 or SHA512, weaker hashes are not
+// trusted.
+func (rf *ReleaseFile) VerifyFile(path string, data []byte) error {
+	rc, field := rf.Checksum(path)
+	if rc == nil {
+		return fmt.Errorf("%s is not listed in Release", path)
+	}
+	if !in(field, releaseStrongHashFields) {
+		return fmt.Errorf("%s is listed in Release with %s only, SHA256 is required", path, field)
+	}
+	if rc.size != int64(len(data)) {
+		return fmt.Errorf("%s: size %d, expected %d", path, len(data), rc.size)
+	}
+	h := newHash(releaseHashTypes[field])
+	h.Write(data)
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, rc.sum) {
+		return fmt.Errorf("%s: %s %s, expected %s", path, field, sum, rc.sum)
+	}
+	return nil
+}
+
+// WriteTo writes the Release file.
+func (rf *ReleaseFile) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	buf.WriteString(rf.fields.String())
+	for _, field := range releaseHashFields {
+		sums := rf.Checksums(field)
+		if len(sums) == 0 {
+			continue
+		}
+		width := 0
+		for _, rc := range sums {
+			if l := len(strconv.FormatInt(rc.size, 10)); l > width {
+				width = l
+			}
+		}
+		buf.WriteString(field + ":\n")
+		for _, rc := range sums {
+			buf.WriteString(fmt.Sprintf(" %s %*d %s\n", rc.sum, width, rc.size, rc.path))
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the Release file content.
+func (rf *ReleaseFile) Bytes() []byte {
+	var buf bytes.Buffer
+	rf.WriteTo(&buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,61 @@
+package deb
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestReleaseVerifyFile(t *testing.T) {
+	index := []byte("Package: hello\nVersion: 1.0-1\n")
+	path := "main/binary-amd64/Packages"
+	sums := map[string]string{}
+	for field, sum := range map[string][]byte{
+		"MD5Sum": func() []byte { s := md5.Sum(index); return s[:] }(),
+		"SHA1":   func() []byte { s := sha1.Sum(index); return s[:] }(),
+		"SHA256": func() []byte { s := sha256.Sum256(index); return s[:] }(),
+		"SHA512": func() []byte { s := sha512.Sum512(index); return s[:] }(),
+	} {
+		sums[field] = hex.EncodeToString(sum)
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		data   []byte
+		err    string
+	}{
+		{"SHA256", []string{"MD5Sum", "SHA256"}, index, ""},
+		{"SHA512 only", []string{"SHA512"}, index, ""},
+		{"MD5 only", []string{"MD5Sum"}, index, "SHA256 is required"},
+		{"SHA1 only", []string{"MD5Sum", "SHA1"}, index, "SHA256 is required"},
+		{"size mismatch", []string{"SHA256"}, append(index, '\n'), "size"},
+		{"hash mismatch", []string{"SHA256"}, []byte(strings.Replace(string(index), "1.0", "2.0", 1)), "SHA256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := NewReleaseFile()
+			for _, field := range tt.fields {
+				rf.AddChecksum(field, path, int64(len(index)), sums[field])
+			}
+			parsed, err := ParseReleaseFile(rf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = parsed.VerifyFile(path, tt.data)
+			if tt.err == "" && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("error %v, expected %q", err, tt.err)
+			}
+		})
+	}
+	if err := NewReleaseFile().VerifyFile(path, index); err == nil {
+		t.Errorf("unlisted file verified")
+	}
+}
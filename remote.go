@@ -0,0 +1,394 @@
+package deb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPOptions configure fetching packages over HTTP.
+type HTTPOptions struct {
+	// Client sends requests. If nil, a client with a response header
+	// timeout and the Proxy below is used.
+	Client *http.Client
+
+	// Proxy URL, e.g. "http://proxy:3128". If empty, the environment
+	// variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used.
+	// It is ignored if a Client is given.
+	Proxy string
+
+	// Header is added to every request, e.g. "Authorization".
+	Header http.Header
+
+	// Basic authentication, if Username is set.
+	Username string
+	Password string
+
+	// Number of retries of failed requests and interrupted downloads, and
+	// the delay before the first retry, doubled for each next one.
+	Retries int
+	Backoff time.Duration
+}
+
+var DefaultHTTPOptions = &HTTPOptions{
+	Retries: 3,
+	Backoff: time.Second,
+}
+
+const (
+	// Timeout of waiting for response headers of the default client
+	HTTP_HEADER_TIMEOUT = 30 * time.Second
+
+	// Minimal size of range requests of meta-data reads
+	HTTP_RANGE_CHUNK = 64 << 10
+)
+
+// HTTPStatusError is returned if a server responds with an unexpected status.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.URL, e.Status)
+}
+
+// Temporary returns true if the request may succeed if retried.
+func (e *HTTPStatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// OpenPackageURL reads a package from an HTTP URL. The HTTP options of
+// opts are used, or DefaultHTTPOptions. If only meta-data is requested,
+// the package is read with range requests up to the data archive.
+func OpenPackageURL(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error) {
+	fetcher, err := newHTTPFetcher(opts.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MetaOnly {
+		return fetcher.openMeta(ctx, uri, opts)
+	}
+	body, err := fetcher.open(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return readHTTPBody(body, uri, opts)
+}
+
+// Read a package from a response body
+func readHTTPBody(body *httpBody, uri string, opts *PackageOptions) (*PackageFile, error) {
+	defer body.Close()
+
+	p, err := NewPackageFileReader(body).SetOptions(opts).Read()
+	if err != nil {
+		return nil, err
+	}
+	// Drain to learn the size, if the server did not tell
+	if body.size < 0 {
+		if _, err := io.Copy(ioutil.Discard, body); err != nil {
+			return nil, err
+		}
+		body.size = body.offset
+	}
+	p.setPath(uri).fileSize = uint64(body.size)
+	p.fileTime = body.modTime
+	return p, nil
+}
+
+// Sends requests of HTTP options
+type httpFetcher struct {
+	opts   *HTTPOptions
+	client *http.Client
+}
+
+func newHTTPFetcher(opts *HTTPOptions) (*httpFetcher, error) {
+	if opts == nil {
+		opts = DefaultHTTPOptions
+	}
+	hf := &httpFetcher{opts: opts, client: opts.Client}
+	if hf.client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = HTTP_HEADER_TIMEOUT
+		if opts.Proxy != "" {
+			proxy, err := url.Parse(opts.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("proxy: %s", err.Error())
+			}
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+		hf.client = &http.Client{Transport: transport}
+	}
+	return hf, nil
+}
+
+// Get a URL from an offset up to an inclusive end, or to the end of the
+// file if it is negative, retrying temporary failures. Ranges are only
+// served if the resource still matches the validator, an ETag or date.
+func (hf *httpFetcher) get(ctx context.Context, uri string, offset int64, end int64, validator string) (*http.Response, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var resp *http.Response
+		if resp, err = hf.request(ctx, uri, offset, end, validator); err == nil {
+			return resp, nil
+		}
+		if serr, ok := err.(*HTTPStatusError); (ok && !serr.Temporary()) || ctx.Err() != nil || attempt >= hf.opts.Retries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(hf.opts.Backoff << uint(attempt)):
+		}
+	}
+}
+
+// Send a single request, checking the status. A full response to a range
+// from the start of the file is accepted, as servers may ignore ranges.
+func (hf *httpFetcher) request(ctx context.Context, uri string, offset int64, end int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range hf.opts.Header {
+		req.Header[name] = append([]string{}, values...)
+	}
+	if hf.opts.Username != "" {
+		req.SetBasicAuth(hf.opts.Username, hf.opts.Password)
+	}
+	expected := http.StatusOK
+	if offset > 0 || end >= 0 {
+		if end >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, end))
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+		expected = http.StatusPartialContent
+	}
+
+	resp, err := hf.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 && resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	if resp.StatusCode != expected {
+		resp.Body.Close()
+		if offset > 0 && resp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("GET %s: cannot resume at %d, the server ignored the range or the file changed", uri, offset)
+		}
+		return nil, &HTTPStatusError{URL: uri, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if expected == http.StatusPartialContent && contentRangeStart(resp.Header.Get("Content-Range")) != offset {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected Content-Range '%s'", uri, resp.Header.Get("Content-Range"))
+	}
+	return resp, nil
+}
+
+// Open a body which resumes interrupted downloads with range requests
+func (hf *httpFetcher) open(ctx context.Context, uri string) (*httpBody, error) {
+	resp, err := hf.get(ctx, uri, 0, -1, "")
+	if err != nil {
+		return nil, err
+	}
+	return hf.newBody(ctx, uri, resp), nil
+}
+
+// Body of a full response
+func (hf *httpFetcher) newBody(ctx context.Context, uri string, resp *http.Response) *httpBody {
+	body := &httpBody{ctx: ctx, fetcher: hf, uri: uri, body: resp.Body, size: resp.ContentLength}
+	body.validator, body.modTime = responseValidator(resp)
+	return body
+}
+
+// Validator of ranges of a response, ETag or Last-Modified, and the latter as time
+func responseValidator(resp *http.Response) (string, time.Time) {
+	var modTime time.Time
+	validator := resp.Header.Get("ETag")
+	if strings.HasPrefix(validator, "W/") {
+		validator = "" // weak ETags are not allowed with ranges
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		modTime, _ = http.ParseTime(lm) // ignore malformed timestamps
+		if validator == "" {
+			validator = lm
+		}
+	}
+	return validator, modTime
+}
+
+// Start offset of a Content-Range header value, e.g. "bytes 100-199/200"
+func contentRangeStart(value string) int64 {
+	value = strings.TrimPrefix(value, "bytes ")
+	if idx := strings.Index(value, "-"); idx > 0 {
+		if start, err := strconv.ParseInt(value[:idx], 10, 64); err == nil {
+			return start
+		}
+	}
+	return -1
+}
+
+// Complete length of a Content-Range header value, or -1 if unknown
+func contentRangeSize(value string) int64 {
+	if idx := strings.LastIndex(value, "/"); idx > -1 {
+		if size, err := strconv.ParseInt(value[idx+1:], 10, 64); err == nil {
+			return size
+		}
+	}
+	return -1
+}
+
+// Response body of a package download
+type httpBody struct {
+	ctx       context.Context
+	fetcher   *httpFetcher
+	uri       string
+	body      io.ReadCloser
+	offset    int64
+	size      int64
+	validator string
+	modTime   time.Time
+	resumes   int
+}
+
+func (hb *httpBody) Read(p []byte) (int, error) {
+	n, err := hb.body.Read(p)
+	hb.offset += int64(n)
+	if err == io.EOF && (hb.size < 0 || hb.offset >= hb.size) {
+		return n, err
+	}
+	if err != nil && hb.ctx.Err() == nil && hb.resumes < hb.fetcher.opts.Retries {
+		// Interrupted or truncated, continue where the body stopped
+		hb.resumes++
+		hb.body.Close()
+		resp, rerr := hb.fetcher.get(hb.ctx, hb.uri, hb.offset, -1, hb.validator)
+		if rerr != nil {
+			return n, rerr
+		}
+		hb.body = resp.Body
+		return n, nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (hb *httpBody) Close() error {
+	return hb.body.Close()
+}
+
+// Sizes of the ar archive format
+const (
+	arMagic      = "!<arch>\n"
+	arHeaderSize = 60
+)
+
+// Ranges of a remote file read from its start
+type httpRangeReader struct {
+	ctx       context.Context
+	fetcher   *httpFetcher
+	uri       string
+	data      []byte
+	size      int64
+	validator string
+	modTime   time.Time
+}
+
+// Fetch data up to an offset, at least a chunk at a time. It returns a
+// full response instead, if the server does not support ranges.
+func (rr *httpRangeReader) fetch(end int64) (*http.Response, error) {
+	if int64(len(rr.data)) >= end || (rr.size > -1 && int64(len(rr.data)) >= rr.size) {
+		return nil, nil
+	}
+	if end-int64(len(rr.data)) < HTTP_RANGE_CHUNK {
+		end = int64(len(rr.data)) + HTTP_RANGE_CHUNK
+	}
+	resp, err := rr.fetcher.get(rr.ctx, rr.uri, int64(len(rr.data)), end-1, rr.validator)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if len(rr.data) == 0 {
+		rr.size = contentRangeSize(resp.Header.Get("Content-Range"))
+		rr.validator, rr.modTime = responseValidator(resp)
+	}
+	chunk, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	rr.data = append(rr.data, chunk...)
+	return nil, nil
+}
+
+// Read meta-data of a package with range requests, fetching members of the
+// ar archive until the data archive. Servers ignoring ranges send the whole
+// package, which is read as usual.
+func (hf *httpFetcher) openMeta(ctx context.Context, uri string, opts *PackageOptions) (*PackageFile, error) {
+	rr := &httpRangeReader{ctx: ctx, fetcher: hf, uri: uri, size: -1}
+	offset := int64(len(arMagic))
+	for {
+		resp, err := rr.fetch(offset + arHeaderSize)
+		if err != nil {
+			return nil, err
+		}
+		if resp != nil {
+			return readHTTPBody(hf.newBody(ctx, uri, resp), uri, opts)
+		}
+		if offset == int64(len(arMagic)) && strings.HasPrefix(string(rr.data), "\x1f\x8b") {
+			// Gzipped tar archive of opkg, members can not be skipped
+			body, err := hf.open(ctx, uri)
+			if err != nil {
+				return nil, err
+			}
+			return readHTTPBody(body, uri, opts)
+		}
+		if offset == int64(len(arMagic)) && !strings.HasPrefix(string(rr.data), arMagic) {
+			return nil, fmt.Errorf("%s: not an ar archive", uri)
+		}
+		if int64(len(rr.data)) < offset+arHeaderSize {
+			break // end of the archive
+		}
+		name, size, err := arMemberHeader(rr.data[offset : offset+arHeaderSize])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", uri, err.Error())
+		}
+		if strings.HasPrefix(name, "data.") {
+			break
+		}
+		offset += arHeaderSize + size + size%2
+		if _, err = rr.fetch(offset); err != nil {
+			return nil, err
+		}
+		if int64(len(rr.data)) < offset {
+			return nil, fmt.Errorf("%s: truncated ar member '%s'", uri, name)
+		}
+	}
+
+	p, err := NewPackageFileReader(bytes.NewReader(rr.data[:offset])).SetOptions(opts).SetMetaonly(true).Read()
+	if err != nil {
+		return nil, err
+	}
+	p.setPath(uri).fileTime = rr.modTime
+	if rr.size > -1 {
+		p.fileSize = uint64(rr.size)
+	}
+	return p, nil
+}
@@ -0,0 +1,259 @@
+package deb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Options retrying quickly
+var testHTTPOptions = &HTTPOptions{Retries: 2, Backoff: time.Millisecond}
+
+// Package with a data archive larger than a range chunk
+func testLargePackage(t *testing.T, write func(*PackageBuilder, *bytes.Buffer) error) []byte {
+	data := make([]byte, 4*HTTP_RANGE_CHUNK)
+	rand.New(rand.NewSource(1)).Read(data)
+	var buf bytes.Buffer
+	if err := write(testBuilder().AddFile("/usr/share/hello/blob", 0644, data), &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testWriteDeb(pb *PackageBuilder, buf *bytes.Buffer) error { return pb.WriteDeb(buf) }
+func testWriteIPK(pb *PackageBuilder, buf *bytes.Buffer) error { return pb.WriteIPK(buf) }
+
+// Request of a test server
+type testRequest struct {
+	rangeHeader string
+	ifRange     string
+}
+
+// Server of a package, recording requests and bytes sent
+type testServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []testRequest
+	sent     int
+}
+
+// Writer counting bytes of a response
+type countingWriter struct {
+	http.ResponseWriter
+	ts *testServer
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(p)
+	cw.ts.mu.Lock()
+	cw.ts.sent += n
+	cw.ts.mu.Unlock()
+	return n, err
+}
+
+// Start a server with a handler of the request number
+func newTestServer(t *testing.T, handler func(n int, w http.ResponseWriter, r *http.Request)) *testServer {
+	ts := new(testServer)
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		ts.requests = append(ts.requests, testRequest{rangeHeader: r.Header.Get("Range"), ifRange: r.Header.Get("If-Range")})
+		n := len(ts.requests)
+		ts.mu.Unlock()
+		handler(n, countingWriter{ResponseWriter: w, ts: ts}, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// Requests received and bytes sent so far
+func (ts *testServer) log() ([]testRequest, int) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]testRequest{}, ts.requests...), ts.sent
+}
+
+// Serve content with ranges and an ETag
+func serveTestContent(w http.ResponseWriter, r *http.Request, etag string, content []byte) {
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, "", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), bytes.NewReader(content))
+}
+
+func TestOpenPackageURLResume(t *testing.T) {
+	content := testLargePackage(t, testWriteDeb)
+	ts := newTestServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			// Interrupt the first download in the middle
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			panic(http.ErrAbortHandler)
+		}
+		serveTestContent(w, r, `"v1"`, content)
+	})
+
+	opts := &PackageOptions{Hash: HASH_MD5, Hashes: []int{HASH_SHA256}, HTTP: testHTTPOptions}
+	pf, err := OpenPackageURL(context.Background(), ts.URL+"/hello.deb", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, _ := ts.log()
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, expected 2", len(requests))
+	}
+	if req := requests[1]; !strings.HasPrefix(req.rangeHeader, "bytes=") || req.rangeHeader == "bytes=0-" || req.ifRange != `"v1"` {
+		t.Errorf("resumed with Range %q and If-Range %q", req.rangeHeader, req.ifRange)
+	}
+	sum := sha256.Sum256(content)
+	if got := pf.PackageChecksums()[HASH_SHA256]; got != hex.EncodeToString(sum[:]) {
+		t.Errorf("SHA256 %s of the resumed package, expected %s", got, hex.EncodeToString(sum[:]))
+	}
+	if pf.FileSize() != uint64(len(content)) {
+		t.Errorf("size %d, expected %d", pf.FileSize(), len(content))
+	}
+	if pf.ControlFile().Package() != "hello" {
+		t.Errorf("package %q", pf.ControlFile().Package())
+	}
+}
+
+func TestOpenPackageURLResumeChanged(t *testing.T) {
+	content := testLargePackage(t, testWriteDeb)
+	ts := newTestServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			panic(http.ErrAbortHandler)
+		}
+		// The file changed, so If-Range fails and the whole file is sent
+		serveTestContent(w, r, `"v2"`, content)
+	})
+
+	opts := &PackageOptions{Hash: HASH_MD5, HTTP: testHTTPOptions}
+	if _, err := OpenPackageURL(context.Background(), ts.URL+"/hello.deb", opts); err == nil || !strings.Contains(err.Error(), "cannot resume") {
+		t.Errorf("resumed a changed file: %v", err)
+	}
+}
+
+func TestOpenPackageURLRetry(t *testing.T) {
+	content := testLargePackage(t, testWriteDeb)
+	ts := newTestServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		serveTestContent(w, r, `"v1"`, content)
+	})
+
+	opts := &PackageOptions{Hash: HASH_MD5, HTTP: testHTTPOptions}
+	if _, err := OpenPackageURL(context.Background(), ts.URL+"/hello.deb", opts); err != nil {
+		t.Fatal(err)
+	}
+	if requests, _ := ts.log(); len(requests) != 2 {
+		t.Errorf("%d requests, expected 2", len(requests))
+	}
+}
+
+func TestOpenPackageURLNotFound(t *testing.T) {
+	ts := newTestServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	opts := &PackageOptions{Hash: HASH_MD5, HTTP: testHTTPOptions}
+	_, err := OpenPackageURL(context.Background(), ts.URL+"/hello.deb", opts)
+	if serr, ok := err.(*HTTPStatusError); !ok || serr.StatusCode != http.StatusNotFound {
+		t.Errorf("error %v, expected status 404", err)
+	}
+	if requests, _ := ts.log(); len(requests) != 1 {
+		t.Errorf("%d requests, permanent failures must not be retried", len(requests))
+	}
+}
+
+func TestOpenPackageURLMetaOnly(t *testing.T) {
+	content := testLargePackage(t, testWriteDeb)
+	ts := newTestServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		serveTestContent(w, r, `"v1"`, content)
+	})
+
+	opts := &PackageOptions{Hash: HASH_MD5, MetaOnly: true, HTTP: testHTTPOptions}
+	pf, err := OpenPackageURL(context.Background(), ts.URL+"/hello.deb", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf.ControlFile().Package() != "hello" || pf.PostInstallScript() == "" {
+		t.Errorf("meta-data was not read")
+	}
+	if len(pf.Files()) != 0 {
+		t.Errorf("data archive was read")
+	}
+	if pf.FileSize() != uint64(len(content)) {
+		t.Errorf("size %d, expected %d", pf.FileSize(), len(content))
+	}
+	requests, sent := ts.log()
+	if sent >= len(content)/2 {
+		t.Errorf("%d of %d bytes fetched for meta-data", sent, len(content))
+	}
+	for idx, req := range requests {
+		if req.rangeHeader == "" {
+			t.Errorf("request without a range")
+		}
+		if idx > 0 && req.ifRange != `"v1"` {
+			t.Errorf("range %q without If-Range", req.rangeHeader)
+		}
+	}
+}
+
+func TestOpenPackageURLMetaOnlyNoRanges(t *testing.T) {
+	content := testLargePackage(t, testWriteDeb)
+	ts := newTestServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		// Ranges are ignored, the whole package is sent
+		w.Write(content)
+	})
+
+	opts := &PackageOptions{Hash: HASH_MD5, MetaOnly: true, HTTP: testHTTPOptions}
+	pf, err := OpenPackageURL(context.Background(), ts.URL+"/hello.deb", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf.ControlFile().Package() != "hello" {
+		t.Errorf("package %q", pf.ControlFile().Package())
+	}
+	if requests, _ := ts.log(); len(requests) != 1 {
+		t.Errorf("%d requests, expected 1", len(requests))
+	}
+	if pf.FileSize() != uint64(len(content)) {
+		t.Errorf("size %d, expected %d", pf.FileSize(), len(content))
+	}
+}
+
+func TestOpenPackageURLMetaOnlyIPK(t *testing.T) {
+	content := testLargePackage(t, testWriteIPK)
+	ts := newTestServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		serveTestContent(w, r, `"v1"`, content)
+	})
+
+	opts := &PackageOptions{Hash: HASH_MD5, MetaOnly: true, HTTP: testHTTPOptions}
+	pf, err := OpenPackageURL(context.Background(), ts.URL+"/hello.ipk", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf.Format() != PACKAGE_FORMAT_IPK {
+		t.Errorf("format %q", pf.Format())
+	}
+	if pf.ControlFile().Package() != "hello" || pf.PostInstallScript() == "" {
+		t.Errorf("meta-data was not read")
+	}
+	if pf.FileSize() != uint64(len(content)) {
+		t.Errorf("size %d, expected %d", pf.FileSize(), len(content))
+	}
+}
@@ -0,0 +1,596 @@
+package deb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RepoSuite is a suite (distribution) of a repository, e.g. "stable".
+type RepoSuite struct {
+	Name          string   `json:"name"`
+	Codename      string   `json:"codename,omitempty"`
+	Components    []string `json:"components"`
+	Architectures []string `json:"architectures"`
+}
+
+// RepoConfig is the configuration of a repository, kept in conf/repo.json.
+type RepoConfig struct {
+	Origin      string      `json:"origin,omitempty"`
+	Label       string      `json:"label,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Suites      []RepoSuite `json:"suites"`
+}
+
+// Suite returns a suite by its name or codename, or nil.
+func (rc *RepoConfig) Suite(name string) *RepoSuite {
+	for idx := range rc.Suites {
+		if rc.Suites[idx].Name == name || (name != "" && rc.Suites[idx].Codename == name) {
+			return &rc.Suites[idx]
+		}
+	}
+	return nil
+}
+
+// Repository is an APT repository with the usual layout:
+//
+//	conf/repo.json                                   configuration
+//	pool/<component>/<prefix>/<source>/*.deb          package files
+//	dists/<suite>/<component>/binary-<arch>/Packages  package indices
+//	dists/<suite>/<component>/Contents-<arch>.gz      contents indices
+//	dists/<suite>/Release                             suite index
+//
+// Uncompressed Packages indices hold the list of packages in a suite, while
+// compressed indices, Contents and Release files are generated with Index.
+// A suite holds one version of a package per component and architecture.
+type Repository struct {
+	root   string
+	config *RepoConfig
+}
+
+// Path of the configuration file within the repository
+const repoConfigPath = "conf/repo.json"
+
+// InitRepository creates a new repository in a directory.
+func InitRepository(root string, config *RepoConfig) (*Repository, error) {
+	if len(config.Suites) == 0 {
+		return nil, fmt.Errorf("repository must have at least one suite")
+	}
+	for _, suite := range config.Suites {
+		if suite.Name == "" || len(suite.Components) == 0 || len(suite.Architectures) == 0 {
+			return nil, fmt.Errorf("suite '%s' must have a name, components and architectures", suite.Name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, repoConfigPath)); err == nil {
+		return nil, fmt.Errorf("repository already exists in %s", root)
+	}
+
+	repo := &Repository{root: root, config: config}
+	for _, dir := range []string{"conf", "pool", "dists"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.saveConfig(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// OpenRepository opens an existing repository.
+func OpenRepository(root string) (*Repository, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, repoConfigPath))
+	if err != nil {
+		return nil, err
+	}
+	config := new(RepoConfig)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %s", repoConfigPath, err.Error())
+	}
+	return &Repository{root: root, config: config}, nil
+}
+
+// Write configuration file
+func (r *Repository) saveConfig() error {
+	data, err := json.MarshalIndent(r.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(r.root, repoConfigPath), append(data, '\n'), 0644)
+}
+
+// Root returns the repository directory.
+func (r *Repository) Root() string {
+	return r.root
+}
+
+// Config returns the repository configuration.
+func (r *Repository) Config() *RepoConfig {
+	return r.config
+}
+
+// Find suite and check the component
+func (r *Repository) suite(name string, component string) (*RepoSuite, error) {
+	suite := r.config.Suite(name)
+	if suite == nil {
+		return nil, fmt.Errorf("unknown suite '%s'", name)
+	}
+	if component != "" && !in(component, suite.Components) {
+		return nil, fmt.Errorf("suite '%s' has no component '%s'", suite.Name, component)
+	}
+	return suite, nil
+}
+
+// Path of the uncompressed Packages index
+func (r *Repository) packagesPath(suite string, component string, arch string) string {
+	return filepath.Join(r.root, "dists", suite, component, "binary-"+arch, "Packages")
+}
+
+// PackagesIndex returns the packages of a suite component for an architecture.
+func (r *Repository) PackagesIndex(suite string, component string, arch string) (*PackagesIndex, error) {
+	s, err := r.suite(suite, component)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(r.packagesPath(s.Name, component, arch))
+	if os.IsNotExist(err) {
+		return NewPackagesIndex(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParsePackagesIndex(f)
+}
+
+// Write the uncompressed Packages index
+func (r *Repository) savePackagesIndex(suite string, component string, arch string, pi *PackagesIndex) error {
+	target := r.packagesPath(suite, component, arch)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(target, pi.Sort().Bytes(), 0644)
+}
+
+// Package and source names allowed by the Debian policy, and architectures
+var (
+	packageName      = regexp.MustCompile(`^[a-z0-9][a-z0-9.+-]+$`)
+	architectureName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// Check names of a package used in its pool filename, which come from its
+// control file
+func checkPoolNames(name string, source string, version string, arch string) error {
+	if !packageName.MatchString(name) {
+		return fmt.Errorf("invalid package name '%s'", name)
+	}
+	if !packageName.MatchString(source) {
+		return fmt.Errorf("invalid source package name '%s'", source)
+	}
+	if _, err := ParseVersion(version); err != nil || strings.Contains(version, "/") {
+		return fmt.Errorf("invalid version '%s'", version)
+	}
+	if !architectureName.MatchString(arch) {
+		return fmt.Errorf("invalid architecture '%s'", arch)
+	}
+	return nil
+}
+
+// Path of a pool file, which must be within the repository
+func (r *Repository) poolPath(filename string) (string, error) {
+	target := filepath.Join(r.root, filepath.FromSlash(filename))
+	rel, err := filepath.Rel(r.root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("pool file '%s' is outside of the repository", filename)
+	}
+	return target, nil
+}
+
+// Pool directory of a source package, e.g. "pool/main/libf/libfoo"
+func poolDir(component string, source string) string {
+	prefix := source[:1]
+	if strings.HasPrefix(source, "lib") && len(source) > 3 {
+		prefix = source[:4]
+	}
+	return path.Join("pool", component, prefix, source)
+}
+
+// Add a package file to a suite component. The file is copied to the pool
+// and replaces other versions of the package in the suite. Packages of
+// architecture "all" are added to all architectures of the suite.
+func (r *Repository) Add(suite string, component string, debPath string) (*IndexEntry, error) {
+	s, err := r.suite(suite, component)
+	if err != nil {
+		return nil, err
+	}
+	pf, err := OpenPackageFile(debPath, &PackageOptions{MetaOnly: true, Hash: HASH_MD5})
+	if err != nil {
+		return nil, err
+	}
+	cf := pf.ControlFile()
+	if cf.Package() == "" || cf.Version() == "" || cf.Architecture() == "" {
+		return nil, fmt.Errorf("%s: Package, Version and Architecture fields are required", debPath)
+	}
+	archs := []string{cf.Architecture()}
+	if cf.Architecture() == "all" {
+		archs = s.Architectures
+	} else if !in(cf.Architecture(), s.Architectures) {
+		return nil, fmt.Errorf("suite '%s' has no architecture '%s'", s.Name, cf.Architecture())
+	}
+
+	version := cf.Version()
+	if idx := strings.Index(version, ":"); idx > -1 {
+		version = version[idx+1:]
+	}
+	source := cf.Source()
+	if fields := strings.Fields(source); len(fields) > 0 {
+		source = fields[0]
+	} else {
+		source = cf.Package()
+	}
+	if err := checkPoolNames(cf.Package(), source, cf.Version(), cf.Architecture()); err != nil {
+		return nil, fmt.Errorf("%s: %s", debPath, err.Error())
+	}
+	filename := path.Join(poolDir(component, source), fmt.Sprintf("%s_%s_%s.deb", cf.Package(), version, cf.Architecture()))
+	entry, err := newIndexEntry(pf, filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.copyToPool(debPath, filename, entry.Field("SHA256")); err != nil {
+		return nil, err
+	}
+
+	replaced := make([]string, 0)
+	for _, arch := range archs {
+		pi, err := r.PackagesIndex(s.Name, component, arch)
+		if err != nil {
+			return nil, err
+		}
+		for _, ie := range pi.Entries() {
+			if ie.Package() == cf.Package() && ie.Filename() != filename {
+				replaced = append(replaced, ie.Filename())
+			}
+		}
+		pi.Remove(cf.Package(), "", "").Add(entry)
+		if err := r.savePackagesIndex(s.Name, component, arch, pi); err != nil {
+			return nil, err
+		}
+	}
+	return entry, r.prune(replaced)
+}
+
+// Copy package file into the pool. An existing pool file must be the same.
+func (r *Repository) copyToPool(src string, filename string, sha256sum string) error {
+	target, err := r.poolPath(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(target); err == nil {
+		if NewChecksum(target).SHA256() != sha256sum {
+			return fmt.Errorf("%s already exists in the pool with different content", filename)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	out, err := os.Create(target + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, f); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(target+".tmp", target)
+}
+
+// Remove a package from a suite component, from all architectures. Empty
+// version removes any version. Pool files no longer referenced by any suite
+// are deleted. Returns the number of removed index entries.
+func (r *Repository) Remove(suite string, component string, name string, version string) (int, error) {
+	s, err := r.suite(suite, component)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	filenames := make([]string, 0)
+	for _, arch := range s.Architectures {
+		pi, err := r.PackagesIndex(s.Name, component, arch)
+		if err != nil {
+			return removed, err
+		}
+		before := len(pi.Entries())
+		for _, ie := range pi.Entries() {
+			if ie.Package() == name && (version == "" || ie.Version() == version) {
+				filenames = append(filenames, ie.Filename())
+			}
+		}
+		pi.Remove(name, version, "")
+		if len(pi.Entries()) == before {
+			continue
+		}
+		removed += before - len(pi.Entries())
+		if err := r.savePackagesIndex(s.Name, component, arch, pi); err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, r.prune(filenames)
+}
+
+// Delete pool files no longer referenced by any suite
+func (r *Repository) prune(filenames []string) error {
+	if len(filenames) == 0 {
+		return nil
+	}
+	referenced, err := r.referencedFiles()
+	if err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		if referenced[filename] {
+			continue
+		}
+		target, err := r.poolPath(filename)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pool files referenced by any Packages index
+func (r *Repository) referencedFiles() (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	for _, suite := range r.config.Suites {
+		for _, component := range suite.Components {
+			for _, arch := range suite.Architectures {
+				pi, err := r.PackagesIndex(suite.Name, component, arch)
+				if err != nil {
+					return nil, err
+				}
+				for _, ie := range pi.Entries() {
+					referenced[ie.Filename()] = true
+				}
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// Index generates compressed Packages indices, Contents indices and Release
+// files of suites. All suites are indexed if none is given.
+func (r *Repository) Index(suites ...string) error {
+	if len(suites) == 0 {
+		for _, suite := range r.config.Suites {
+			suites = append(suites, suite.Name)
+		}
+	}
+	cache := make(map[string][]string)
+	for _, name := range suites {
+		s, err := r.suite(name, "")
+		if err != nil {
+			return err
+		}
+		if err := r.indexSuite(s, cache); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write file and its gzip compressed copy
+func writeCompressed(target string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(target, data, 0644); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	gzw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	gzw.Write(data)
+	if err := gzw.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(target+".gz", buf.Bytes(), 0644)
+}
+
+// Paths of files of a pool package, read once per indexing run
+func (r *Repository) poolFiles(filename string, cache map[string][]string) ([]string, error) {
+	if files, ok := cache[filename]; ok {
+		return files, nil
+	}
+	target, err := r.poolPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	pf, err := OpenPackageFile(target, &PackageOptions{Hash: HASH_MD5})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	files := make([]string, 0)
+	for _, fi := range pf.Files() {
+		if !fi.Mode().IsDir() {
+			files = append(files, strings.TrimPrefix(absPath(fi.Name()), "/"))
+		}
+	}
+	cache[filename] = files
+	return files, nil
+}
+
+// Contents index of packages: file paths and the packages shipping them.
+// File lists of packages are cached, as packages of architecture "all" and
+// other suites share them.
+func (r *Repository) contentsIndex(pi *PackagesIndex, cache map[string][]string) ([]byte, error) {
+	owners := make(map[string][]string)
+	for _, ie := range pi.Entries() {
+		files, err := r.poolFiles(ie.Filename(), cache)
+		if err != nil {
+			return nil, err
+		}
+		qualified := ie.Package()
+		if section := ie.Field("Section"); section != "" {
+			qualified = section + "/" + qualified
+		}
+		for _, name := range files {
+			if !in(qualified, owners[name]) {
+				owners[name] = append(owners[name], qualified)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(owners))
+	for name := range owners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		buf.WriteString(fmt.Sprintf("%-55s %s\n", name, strings.Join(owners[name], ",")))
+	}
+	return buf.Bytes(), nil
+}
+
+// Generate indices and Release files of a suite, with a cache of file
+// lists of pool packages
+func (r *Repository) indexSuite(s *RepoSuite, cache map[string][]string) error {
+	distDir := filepath.Join(r.root, "dists", s.Name)
+	files := make([]string, 0)
+	for _, component := range s.Components {
+		for _, arch := range s.Architectures {
+			pi, err := r.PackagesIndex(s.Name, component, arch)
+			if err != nil {
+				return err
+			}
+			pi.Sort()
+			binDir := path.Join(component, "binary-"+arch)
+			if err := writeCompressed(filepath.Join(distDir, filepath.FromSlash(binDir), "Packages"), pi.Bytes()); err != nil {
+				return err
+			}
+
+			release := NewReleaseFile()
+			release.SetField("Archive", s.Name)
+			if s.Codename != "" {
+				release.SetField("Codename", s.Codename)
+			}
+			if r.config.Origin != "" {
+				release.SetField("Origin", r.config.Origin)
+			}
+			if r.config.Label != "" {
+				release.SetField("Label", r.config.Label)
+			}
+			release.SetField("Component", component).SetField("Architecture", arch)
+			if err := ioutil.WriteFile(filepath.Join(distDir, filepath.FromSlash(binDir), "Release"), release.Bytes(), 0644); err != nil {
+				return err
+			}
+			files = append(files, path.Join(binDir, "Packages"), path.Join(binDir, "Packages.gz"), path.Join(binDir, "Release"))
+
+			contents, err := r.contentsIndex(pi, cache)
+			if err != nil {
+				return err
+			}
+			contentsPath := path.Join(component, "Contents-"+arch)
+			if err := writeCompressed(filepath.Join(distDir, filepath.FromSlash(contentsPath)), contents); err != nil {
+				return err
+			}
+			files = append(files, contentsPath, contentsPath+".gz")
+		}
+	}
+
+	release := NewReleaseFile()
+	for _, field := range [][]string{{"Origin", r.config.Origin}, {"Label", r.config.Label}, {"Suite", s.Name}, {"Codename", s.Codename}} {
+		if field[1] != "" {
+			release.SetField(field[0], field[1])
+		}
+	}
+	release.SetField("Date", time.Now().UTC().Format(time.RFC1123))
+	release.SetField("Architectures", strings.Join(s.Architectures, " "))
+	release.SetField("Components", strings.Join(s.Components, " "))
+	if r.config.Description != "" {
+		release.SetField("Description", r.config.Description)
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(distDir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		for idx, h := range []hash.Hash{md5.New(), sha1.New(), sha256.New()} {
+			h.Write(data)
+			release.AddChecksum(releaseHashFields[idx], name, int64(len(data)), hex.EncodeToString(h.Sum(nil)))
+		}
+	}
+
+	// Signatures of the previous Release are no longer valid
+	for _, name := range []string{"InRelease", "Release.gpg"} {
+		if err := os.Remove(filepath.Join(distDir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return ioutil.WriteFile(filepath.Join(distDir, "Release"), release.Bytes(), 0644)
+}
+
+// Sign Release files of suites with the first secret key of an armored
+// keyring, writing detached Release.gpg and inline-signed InRelease files.
+// All suites are signed if none is given.
+func (r *Repository) Sign(keyring io.Reader, passphrase []byte, suites ...string) error {
+	signer, err := ReadSigningKey(keyring, passphrase)
+	if err != nil {
+		return err
+	}
+	if len(suites) == 0 {
+		for _, suite := range r.config.Suites {
+			suites = append(suites, suite.Name)
+		}
+	}
+	for _, name := range suites {
+		s, err := r.suite(name, "")
+		if err != nil {
+			return err
+		}
+		distDir := filepath.Join(r.root, "dists", s.Name)
+		release, err := ioutil.ReadFile(filepath.Join(distDir, "Release"))
+		if err != nil {
+			return fmt.Errorf("suite '%s' is not indexed: %s", s.Name, err.Error())
+		}
+		detached, inline, err := SignRelease(release, signer)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(distDir, "Release.gpg"), detached, 0644); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(distDir, "InRelease"), inline, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blakesmith/ar"
+)
+
+// Repository with a suite "stable" of component "main" for amd64
+func testRepository(t *testing.T) (*Repository, string) {
+	dir := t.TempDir()
+	config := &RepoConfig{Origin: "Test", Suites: []RepoSuite{{Name: "stable", Codename: "test",
+		Components: []string{"main"}, Architectures: []string{"amd64"}}}}
+	repo, err := InitRepository(filepath.Join(dir, "repo"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, dir
+}
+
+// Write a package of a builder into a directory
+func testPackagePath(t *testing.T, dir string, pb *PackageBuilder) string {
+	f, err := os.CreateTemp(dir, "*.deb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := pb.WriteDeb(f); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+// Entry of a tar archive with its content
+type testTarEntry struct {
+	header tar.Header
+	data   []byte
+}
+
+// Gzipped tar archive of entries, sized by their content
+func testTarGz(entries ...testTarEntry) []byte {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, entry := range entries {
+		entry.header.Size = int64(len(entry.data))
+		tw.WriteHeader(&entry.header)
+		tw.Write(entry.data)
+	}
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+// Write a deb package of raw control and data archives into a directory
+func testRawDebPath(t *testing.T, dir string, controlTarGz []byte, dataTarGz []byte) string {
+	path := filepath.Join(dir, "raw.deb")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	aw := ar.NewWriter(f)
+	aw.WriteGlobalHeader()
+	for _, member := range []struct {
+		name string
+		data []byte
+	}{{"debian-binary", []byte("2.0\n")}, {"control.tar.gz", controlTarGz}, {"data.tar.gz", dataTarGz}} {
+		aw.WriteHeader(&ar.Header{Name: member.name, Mode: 0100644, Size: int64(len(member.data))})
+		aw.Write(member.data)
+	}
+	return path
+}
+
+// Write a package with a raw control file, which the builder would reject
+func testRawPackagePath(t *testing.T, dir string, control string) string {
+	controlTarGz := testTarGz(testTarEntry{tar.Header{Name: "./control", Mode: 0644, Typeflag: tar.TypeReg}, []byte(control)})
+	return testRawDebPath(t, dir, controlTarGz, testTarGz())
+}
+
+func TestRepositoryAddCraftedNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		value string
+	}{
+		{"package traversal", "Package", "../../../../tmp/x"},
+		{"package with slash", "Package", "hello/x"},
+		{"source traversal", "Source", "../.."},
+		{"source with version and traversal", "Source", "../../x (1.0)"},
+		{"architecture traversal", "Architecture", "../amd64"},
+		{"version with slash", "", "Package: hello\nVersion: 1.0/../../../../x\nArchitecture: amd64\n"},
+		{"version traversal", "", "Package: hello\nVersion: ../../../../../x\nArchitecture: amd64\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, dir := testRepository(t)
+			pkgDir := t.TempDir()
+			var debPath string
+			if tt.field == "" {
+				debPath = testRawPackagePath(t, pkgDir, tt.value)
+			} else {
+				debPath = testPackagePath(t, pkgDir, testBuilder().SetField(tt.field, tt.value))
+			}
+			if _, err := repo.Add("stable", "main", debPath); err == nil {
+				t.Errorf("package with %s %q added", tt.field, tt.value)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Name() != "repo" {
+				t.Errorf("files written outside of the repository: %v", entries)
+			}
+		})
+	}
+}
+
+func TestRepositoryPoolPath(t *testing.T) {
+	repo, _ := testRepository(t)
+	for _, filename := range []string{"../x.deb", "pool/../../x.deb", ".."} {
+		if _, err := repo.poolPath(filename); err == nil {
+			t.Errorf("pool path of '%s' accepted", filename)
+		}
+	}
+	if _, err := repo.poolPath("pool/main/h/hello/hello_1.0-1_amd64.deb"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRepositorySign(t *testing.T) {
+	repo, _ := testRepository(t)
+	key := testKey(t, time.Now().Add(-time.Hour), 0)
+	secret := testSecretKeyring(t, key, nil)
+	if err := repo.Sign(bytes.NewReader(secret), nil); err == nil {
+		t.Errorf("suite signed before indexing")
+	}
+
+	debPath := testPackagePath(t, t.TempDir(), testBuilder())
+	ie, err := repo.Add("stable", "main", debPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Index(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Sign(bytes.NewReader(secret), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	suiteDir := filepath.Join(repo.root, "dists", "stable")
+	pf, err := OpenPackageFile(filepath.Join(repo.root, filepath.FromSlash(ie.Filename())), DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inRelease := range []bool{true, false} {
+		if !inRelease {
+			// Fall back to Release and Release.gpg
+			if err := os.Remove(filepath.Join(suiteDir, "InRelease")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		vi, err := OpenVerifiedIndex(bytes.NewReader(testPublicKeyring(t, key)), suiteDir, "main/binary-amd64/Packages", nil)
+		if err != nil {
+			t.Fatalf("InRelease %v: %v", inRelease, err)
+		}
+		if vi.Release().Suite() != "stable" || vi.Release().Codename() != "test" {
+			t.Errorf("release of suite %q codename %q", vi.Release().Suite(), vi.Release().Codename())
+		}
+		if _, err := vi.VerifyPackage(pf); err != nil {
+			t.Errorf("InRelease %v: %v", inRelease, err)
+		}
+	}
+}
@@ -0,0 +1,654 @@
+package deb
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File of a component in a bill of materials
+type sbomFile struct {
+	path      string
+	checksums [][2]string // Hash name and checksum
+}
+
+// Checksum of a file by hash name, or an empty string
+func (sf *sbomFile) checksum(hash string) string {
+	for _, cs := range sf.checksums {
+		if cs[0] == hash {
+			return cs[1]
+		}
+	}
+	return ""
+}
+
+// Package in a bill of materials
+type sbomComponent struct {
+	name        string
+	version     string
+	arch        string
+	maintainer  string
+	description string
+	homepage    string
+	license     string
+	checksums   [][2]string // Hash name and checksum
+	files       []sbomFile
+	relations   []RelationGroup
+	provides    []string
+}
+
+// Create component out of control stanza fields
+func newSbomComponent(fields fieldSource) *sbomComponent {
+	sc := &sbomComponent{
+		name:        fields.Field("Package"),
+		version:     fields.Field("Version"),
+		arch:        fields.Field("Architecture"),
+		maintainer:  fields.Field("Maintainer"),
+		description: strings.SplitN(fields.Field("Description"), "\n", 2)[0],
+		homepage:    fields.Field("Homepage"),
+		checksums:   make([][2]string, 0),
+		files:       make([]sbomFile, 0),
+		relations:   make([]RelationGroup, 0),
+		provides:    make([]string, 0),
+	}
+	for _, name := range []string{"Pre-Depends", "Depends"} {
+		if groups, err := ParseRelations(fields.Field(name)); err == nil {
+			sc.relations = append(sc.relations, groups...)
+		}
+	}
+	if groups, err := ParseRelations(fields.Field("Provides")); err == nil {
+		for idx := range groups {
+			for _, rel := range groups[idx].Alternatives() {
+				sc.provides = append(sc.provides, rel.Name())
+			}
+		}
+	}
+	return sc
+}
+
+// SBOM is a software bill of materials of Debian packages, e.g. packages
+// installed in a container image, which can be written as SPDX 2.3 or
+// CycloneDX 1.5 document.
+type SBOM struct {
+	name       string
+	distro     string
+	created    time.Time
+	components []*sbomComponent
+}
+
+// NewSBOM constructor. The name describes the subject of the document,
+// e.g. an image name.
+func NewSBOM(name string) *SBOM {
+	s := new(SBOM)
+	s.name = name
+	s.distro = "debian"
+	s.created = time.Now().UTC()
+	s.components = make([]*sbomComponent, 0)
+	return s
+}
+
+// SetDistribution sets the vendor of the packages used in package URLs,
+// "debian" by default.
+func (s *SBOM) SetDistribution(distro string) *SBOM {
+	s.distro = distro
+	return s
+}
+
+// SetCreated sets the creation time of the document, now by default.
+func (s *SBOM) SetCreated(created time.Time) *SBOM {
+	s.created = created.UTC()
+	return s
+}
+
+// AddPackageFile adds a package. Checksums of the package are included if it
+// was opened from a local file, and checksums of its files if they were processed.
+// SPDX requires SHA1 checksums of files, so files are in SPDX documents only
+// if HASH_SHA1 is the hash or one of the additional hashes of the package options.
+func (s *SBOM) AddPackageFile(pf *PackageFile) *SBOM {
+	sc := newSbomComponent(pf.ControlFile())
+	sc.license = pf.CopyrightFile().License()
+	if fi, err := os.Stat(pf.Path()); err == nil && fi.Mode().IsRegular() {
+		cs := pf.GetPackageChecksum()
+		sc.checksums = append(sc.checksums, [2]string{"MD5", cs.MD5()}, [2]string{"SHA1", cs.SHA1()}, [2]string{"SHA256", cs.SHA256()})
+	}
+	for _, fi := range pf.Files() {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		sf := sbomFile{path: fi.Name(), checksums: make([][2]string, 0)}
+		sums := pf.GetCalculatedChecksums(fi.Name())
+		for _, hash := range []int{HASH_SHA1, HASH_SHA256, HASH_SHA512, HASH_BLAKE2B, HASH_MD5} {
+			if sum := sums[hash]; sum != "" {
+				sf.checksums = append(sf.checksums, [2]string{HashName(hash), sum})
+			}
+		}
+		if len(sf.checksums) > 0 {
+			sc.files = append(sc.files, sf)
+		}
+	}
+	s.components = append(s.components, sc)
+	return s
+}
+
+// AddPackageFiles adds several packages.
+func (s *SBOM) AddPackageFiles(pkgs []*PackageFile) *SBOM {
+	for _, pf := range pkgs {
+		s.AddPackageFile(pf)
+	}
+	return s
+}
+
+// AddStatusDB adds installed packages of a dpkg status database. If root is
+// not empty, it is the root directory of the system the database belongs to:
+// licenses are taken from installed copyright files, and file checksums from
+// md5sums files of the dpkg database. Having no SHA1 checksums, files are
+// only in CycloneDX documents.
+func (s *SBOM) AddStatusDB(db *StatusDB, root string) *SBOM {
+	for _, se := range db.Installed() {
+		sc := newSbomComponent(se)
+		if root != "" {
+			if data, err := ioutil.ReadFile(filepath.Join(root, "usr/share/doc", se.Package(), "copyright")); err == nil {
+				sc.license = ParseCopyrightFile(data).License()
+			}
+			sc.files = s.dpkgFileSums(root, se)
+		}
+		s.components = append(s.components, sc)
+	}
+	return s
+}
+
+// File checksums from md5sums of the dpkg database
+func (s *SBOM) dpkgFileSums(root string, se *StatusEntry) []sbomFile {
+	files := make([]sbomFile, 0)
+	f, err := os.Open(se.infoPath(filepath.Join(root, DPKG_INFO_DIR), "md5sums"))
+	if err != nil {
+		return files
+	}
+	defer f.Close()
+
+	scn := bufio.NewScanner(f)
+	for scn.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scn.Text()), " ", 2)
+		if len(parts) == 2 && len(parts[0]) == 0x20 {
+			files = append(files, sbomFile{path: "./" + strings.TrimSpace(parts[1]), checksums: [][2]string{{"MD5", parts[0]}}})
+		}
+	}
+	return files
+}
+
+// Package URL of a component
+func (s *SBOM) purl(sc *sbomComponent) string {
+	purl := fmt.Sprintf("pkg:deb/%s/%s@%s", purlEscape(s.distro), purlEscape(sc.name), purlEscape(sc.version))
+	if sc.arch != "" {
+		purl += "?arch=" + purlEscape(sc.arch)
+	}
+	return purl
+}
+
+// Percent-encode a package URL component
+func purlEscape(value string) string {
+	var buf strings.Builder
+	for _, c := range []byte(value) {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.IndexByte(".-_~", c) > -1 {
+			buf.WriteByte(c)
+		} else {
+			buf.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return buf.String()
+}
+
+// Dependencies of components within the document, by component index.
+// The first alternative of a relation found in the document is taken.
+func (s *SBOM) dependencies() map[int][]int {
+	providers := make(map[string]int)
+	for idx := len(s.components) - 1; idx >= 0; idx-- {
+		for _, name := range s.components[idx].provides {
+			providers[name] = idx
+		}
+	}
+	for idx := len(s.components) - 1; idx >= 0; idx-- {
+		providers[s.components[idx].name] = idx
+	}
+
+	deps := make(map[int][]int)
+	for idx, sc := range s.components {
+		seen := make(map[int]bool)
+		for gidx := range sc.relations {
+			for _, rel := range sc.relations[gidx].Alternatives() {
+				if dep, ok := providers[rel.Name()]; ok && dep != idx {
+					if !seen[dep] {
+						deps[idx] = append(deps[idx], dep)
+						seen[dep] = true
+					}
+					break
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// Random UUID version 4
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Maintainer as "name <email>" split to its parts
+func splitMaintainer(maintainer string) (string, string) {
+	if idx := strings.Index(maintainer, "<"); idx > -1 {
+		return strings.TrimSpace(maintainer[:idx]), strings.Trim(strings.TrimSpace(maintainer[idx:]), "<>")
+	}
+	return strings.TrimSpace(maintainer), ""
+}
+
+// SPDX element identifier
+var spdxIdChars = regexp.MustCompile(`[^A-Za-z0-9.\-]+`)
+
+func spdxId(kind string, parts ...string) string {
+	return "SPDXRef-" + kind + "-" + spdxIdChars.ReplaceAllString(strings.Join(parts, "-"), "-")
+}
+
+// SPDX 2.3 document structures
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxVerificationCode struct {
+	Value string `json:"packageVerificationCodeValue"`
+}
+
+type spdxPackage struct {
+	Name             string                `json:"name"`
+	SPDXID           string                `json:"SPDXID"`
+	VersionInfo      string                `json:"versionInfo,omitempty"`
+	Supplier         string                `json:"supplier,omitempty"`
+	DownloadLocation string                `json:"downloadLocation"`
+	FilesAnalyzed    bool                  `json:"filesAnalyzed"`
+	VerificationCode *spdxVerificationCode `json:"packageVerificationCode,omitempty"`
+	Homepage         string                `json:"homepage,omitempty"`
+	Checksums        []spdxChecksum        `json:"checksums,omitempty"`
+	LicenseConcluded string                `json:"licenseConcluded"`
+	LicenseDeclared  string                `json:"licenseDeclared"`
+	CopyrightText    string                `json:"copyrightText"`
+	Summary          string                `json:"summary,omitempty"`
+	ExternalRefs     []spdxExternalRef     `json:"externalRefs"`
+}
+
+type spdxFile struct {
+	FileName  string         `json:"fileName"`
+	SPDXID    string         `json:"SPDXID"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+type spdxExtractedLicense struct {
+	LicenseId     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+}
+
+type spdxDocument struct {
+	SpdxVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages          []spdxPackage          `json:"packages"`
+	Files             []spdxFile             `json:"files,omitempty"`
+	ExtractedLicenses []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+	Relationships     []spdxRelationship     `json:"relationships"`
+}
+
+var licenseRefs = regexp.MustCompile(`LicenseRef-[A-Za-z0-9.\-]+`)
+
+// SPDX package verification code: SHA1 of sorted SHA1 checksums of files.
+// Files are analyzed only if all of them have SHA1 checksums, otherwise the
+// code is empty.
+func spdxPackageCode(files []sbomFile) string {
+	sums := make([]string, 0, len(files))
+	for idx := range files {
+		sum := strings.ToLower(files[idx].checksum("SHA1"))
+		if sum == "" {
+			return ""
+		}
+		sums = append(sums, sum)
+	}
+	if len(sums) == 0 {
+		return ""
+	}
+	sort.Strings(sums)
+	code := sha1.Sum([]byte(strings.Join(sums, "")))
+	return hex.EncodeToString(code[:])
+}
+
+// Build SPDX document
+func (s *SBOM) spdx() *spdxDocument {
+	doc := &spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              s.name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + spdxIdChars.ReplaceAllString(s.name, "-") + "-" + newUUID(),
+		Packages:          make([]spdxPackage, 0),
+		Files:             make([]spdxFile, 0),
+		ExtractedLicenses: make([]spdxExtractedLicense, 0),
+		Relationships:     make([]spdxRelationship, 0),
+	}
+	doc.CreationInfo.Created = s.created.Format("2006-01-02T15:04:05Z")
+	doc.CreationInfo.Creators = []string{"Tool: go-deb"}
+
+	ids := make([]string, len(s.components))
+	refs := make(map[string]bool)
+	for idx, sc := range s.components {
+		ids[idx] = spdxId("Package", sc.name, sc.arch, fmt.Sprintf("%d", idx))
+		pkg := spdxPackage{
+			Name:             sc.name,
+			SPDXID:           ids[idx],
+			VersionInfo:      sc.version,
+			Supplier:         "NOASSERTION",
+			DownloadLocation: "NOASSERTION",
+			Homepage:         sc.homepage,
+			Checksums:        make([]spdxChecksum, 0),
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+			Summary:          sc.description,
+			ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: s.purl(sc)}},
+		}
+		if name, email := splitMaintainer(sc.maintainer); name != "" {
+			kind := "Person"
+			for _, word := range []string{"team", "maintainers", "developers", "project"} {
+				if strings.Contains(strings.ToLower(name), word) {
+					kind = "Organization"
+				}
+			}
+			pkg.Supplier = fmt.Sprintf("%s: %s", kind, name)
+			if email != "" {
+				pkg.Supplier += " (" + email + ")"
+			}
+		}
+		if sc.license != "" {
+			pkg.LicenseDeclared = sc.license
+			for _, ref := range licenseRefs.FindAllString(sc.license, -1) {
+				if !refs[ref] {
+					refs[ref] = true
+					doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxExtractedLicense{LicenseId: ref,
+						ExtractedText: fmt.Sprintf("See /usr/share/doc/%s/copyright", sc.name)})
+				}
+			}
+		}
+		for _, cs := range sc.checksums {
+			pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: cs[0], ChecksumValue: cs[1]})
+		}
+		code := spdxPackageCode(sc.files)
+		if code != "" {
+			pkg.FilesAnalyzed = true
+			pkg.VerificationCode = &spdxVerificationCode{Value: code}
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SpdxElementId: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSpdxElement: ids[idx]})
+
+		for fidx := 0; pkg.FilesAnalyzed && fidx < len(sc.files); fidx++ {
+			sf := sc.files[fidx]
+			fid := spdxId("File", sc.name, sc.arch, fmt.Sprintf("%d-%d", idx, fidx))
+			file := spdxFile{FileName: sf.path, SPDXID: fid, Checksums: make([]spdxChecksum, 0)}
+			for _, cs := range sf.checksums {
+				file.Checksums = append(file.Checksums, spdxChecksum{Algorithm: cs[0], ChecksumValue: cs[1]})
+			}
+			doc.Files = append(doc.Files, file)
+			doc.Relationships = append(doc.Relationships, spdxRelationship{SpdxElementId: ids[idx], RelationshipType: "CONTAINS", RelatedSpdxElement: fid})
+		}
+	}
+	deps := s.dependencies()
+	for idx := range s.components {
+		for _, dep := range deps[idx] {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{SpdxElementId: ids[idx], RelationshipType: "DEPENDS_ON", RelatedSpdxElement: ids[dep]})
+		}
+	}
+	return doc
+}
+
+// WriteSPDXJSON writes the bill of materials as SPDX 2.3 JSON document.
+func (s *SBOM) WriteSPDXJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.spdx())
+}
+
+// WriteSPDXTagValue writes the bill of materials as SPDX 2.3 tag-value document.
+func (s *SBOM) WriteSPDXTagValue(w io.Writer) error {
+	doc := s.spdx()
+	var out strings.Builder
+	tag := func(name string, value string) {
+		if value == "" {
+			return
+		}
+		if strings.Contains(value, "\n") {
+			value = "<text>" + value + "</text>"
+		}
+		out.WriteString(name + ": " + value + "\n")
+	}
+
+	tag("SPDXVersion", doc.SpdxVersion)
+	tag("DataLicense", doc.DataLicense)
+	tag("SPDXID", doc.SPDXID)
+	tag("DocumentName", doc.Name)
+	tag("DocumentNamespace", doc.DocumentNamespace)
+	for _, creator := range doc.CreationInfo.Creators {
+		tag("Creator", creator)
+	}
+	tag("Created", doc.CreationInfo.Created)
+
+	files := make(map[string]spdxFile)
+	for _, sf := range doc.Files {
+		files[sf.SPDXID] = sf
+	}
+	for _, pkg := range doc.Packages {
+		out.WriteString("\n")
+		tag("PackageName", pkg.Name)
+		tag("SPDXID", pkg.SPDXID)
+		tag("PackageVersion", pkg.VersionInfo)
+		tag("PackageSupplier", pkg.Supplier)
+		tag("PackageDownloadLocation", pkg.DownloadLocation)
+		tag("FilesAnalyzed", fmt.Sprintf("%t", pkg.FilesAnalyzed))
+		if pkg.VerificationCode != nil {
+			tag("PackageVerificationCode", pkg.VerificationCode.Value)
+		}
+		tag("PackageHomePage", pkg.Homepage)
+		for _, cs := range pkg.Checksums {
+			tag("PackageChecksum", cs.Algorithm+": "+cs.ChecksumValue)
+		}
+		tag("PackageLicenseConcluded", pkg.LicenseConcluded)
+		tag("PackageLicenseDeclared", pkg.LicenseDeclared)
+		tag("PackageCopyrightText", pkg.CopyrightText)
+		tag("PackageSummary", pkg.Summary)
+		for _, ref := range pkg.ExternalRefs {
+			tag("ExternalRef", ref.ReferenceCategory+" "+ref.ReferenceType+" "+ref.ReferenceLocator)
+		}
+
+		// Files follow the package containing them
+		for _, rel := range doc.Relationships {
+			if rel.SpdxElementId != pkg.SPDXID || rel.RelationshipType != "CONTAINS" {
+				continue
+			}
+			sf := files[rel.RelatedSpdxElement]
+			out.WriteString("\n")
+			tag("FileName", sf.FileName)
+			tag("SPDXID", sf.SPDXID)
+			for _, cs := range sf.Checksums {
+				tag("FileChecksum", cs.Algorithm+": "+cs.ChecksumValue)
+			}
+		}
+	}
+	for _, lic := range doc.ExtractedLicenses {
+		out.WriteString("\n")
+		tag("LicenseID", lic.LicenseId)
+		tag("ExtractedText", "<text>"+lic.ExtractedText+"</text>")
+	}
+	out.WriteString("\n")
+	for _, rel := range doc.Relationships {
+		tag("Relationship", rel.SpdxElementId+" "+rel.RelationshipType+" "+rel.RelatedSpdxElement)
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// CycloneDX 1.5 document structures
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxComponent struct {
+	Type        string         `json:"type"`
+	BomRef      string         `json:"bom-ref,omitempty"`
+	Supplier    *cdxSupplier   `json:"supplier,omitempty"`
+	Name        string         `json:"name"`
+	Version     string         `json:"version,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Hashes      []cdxHash      `json:"hashes,omitempty"`
+	Licenses    []cdxLicense   `json:"licenses,omitempty"`
+	Purl        string         `json:"purl,omitempty"`
+	Properties  []cdxProperty  `json:"properties,omitempty"`
+	Components  []cdxComponent `json:"components,omitempty"`
+}
+
+type cdxSupplier struct {
+	Name    string       `json:"name"`
+	Contact []cdxContact `json:"contact,omitempty"`
+}
+
+type cdxContact struct {
+	Email string `json:"email"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDX hash algorithm names
+var cdxHashNames = map[string]string{"MD5": "MD5", "SHA1": "SHA-1", "SHA256": "SHA-256", "SHA512": "SHA-512", "BLAKE2b-512": "BLAKE2b-512"}
+
+// WriteCycloneDXJSON writes the bill of materials as CycloneDX 1.5 JSON document.
+func (s *SBOM) WriteCycloneDXJSON(w io.Writer) error {
+	type metadata struct {
+		Timestamp string `json:"timestamp"`
+		Tools     struct {
+			Components []cdxComponent `json:"components"`
+		} `json:"tools"`
+		Component cdxComponent `json:"component"`
+	}
+	doc := struct {
+		BomFormat    string          `json:"bomFormat"`
+		SpecVersion  string          `json:"specVersion"`
+		SerialNumber string          `json:"serialNumber"`
+		Version      int             `json:"version"`
+		Metadata     metadata        `json:"metadata"`
+		Components   []cdxComponent  `json:"components"`
+		Dependencies []cdxDependency `json:"dependencies"`
+	}{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Components:   make([]cdxComponent, 0),
+		Dependencies: make([]cdxDependency, 0),
+	}
+	doc.Metadata.Timestamp = s.created.Format("2006-01-02T15:04:05Z")
+	doc.Metadata.Tools.Components = []cdxComponent{{Type: "application", Name: "go-deb"}}
+	doc.Metadata.Component = cdxComponent{Type: "application", Name: s.name}
+
+	// References must be unique, while the same package may be added twice
+	refs := make([]string, len(s.components))
+	for idx, sc := range s.components {
+		refs[idx] = fmt.Sprintf("component-%d-%s", idx, purlEscape(sc.name))
+		comp := cdxComponent{
+			Type:        "library",
+			BomRef:      refs[idx],
+			Name:        sc.name,
+			Version:     sc.version,
+			Description: sc.description,
+			Purl:        s.purl(sc),
+			Properties:  []cdxProperty{{Name: "deb:architecture", Value: sc.arch}},
+		}
+		if name, email := splitMaintainer(sc.maintainer); name != "" {
+			comp.Supplier = &cdxSupplier{Name: name}
+			if email != "" {
+				comp.Supplier.Contact = []cdxContact{{Email: email}}
+			}
+		}
+		if sc.license != "" {
+			comp.Licenses = []cdxLicense{{Expression: sc.license}}
+		}
+		for _, cs := range sc.checksums {
+			comp.Hashes = append(comp.Hashes, cdxHash{Alg: cdxHashNames[cs[0]], Content: cs[1]})
+		}
+		for _, sf := range sc.files {
+			if strings.HasPrefix(sf.path, "./usr/bin/") || strings.HasPrefix(sf.path, "./usr/sbin/") ||
+				strings.HasPrefix(sf.path, "./bin/") || strings.HasPrefix(sf.path, "./sbin/") {
+				comp.Type = "application"
+			}
+			file := cdxComponent{Type: "file", Name: sf.path}
+			for _, cs := range sf.checksums {
+				file.Hashes = append(file.Hashes, cdxHash{Alg: cdxHashNames[cs[0]], Content: cs[1]})
+			}
+			comp.Components = append(comp.Components, file)
+		}
+		doc.Components = append(doc.Components, comp)
+	}
+
+	deps := s.dependencies()
+	for idx := range s.components {
+		dep := cdxDependency{Ref: refs[idx], DependsOn: make([]string, 0)}
+		for _, didx := range deps[idx] {
+			dep.DependsOn = append(dep.DependsOn, refs[didx])
+		}
+		doc.Dependencies = append(doc.Dependencies, dep)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,171 @@
+package deb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ScanResult is a package read by a Scanner, or the error reading it.
+type ScanResult struct {
+	Path    string
+	Package *PackageFile
+	Err     error
+}
+
+// ScanProgress is called after each package is processed, with the number
+// of packages processed so far. Calls are serialised.
+type ScanProgress func(done int, path string, err error)
+
+// Scanner reads many packages concurrently with a bounded pool of workers.
+type Scanner struct {
+	workers  int
+	opts     *PackageOptions
+	progress ScanProgress
+	suffixes []string
+}
+
+// NewScanner constructor. It uses a worker per CPU and DefaultPackageOptions.
+func NewScanner() *Scanner {
+	s := new(Scanner)
+	s.workers = runtime.NumCPU()
+	s.opts = DefaultPackageOptions
+	s.suffixes = []string{".deb"}
+	return s
+}
+
+// SetWorkers sets the number of packages read at the same time.
+func (s *Scanner) SetWorkers(workers int) *Scanner {
+	if workers < 1 {
+		workers = 1
+	}
+	s.workers = workers
+	return s
+}
+
+// SetOptions sets options of opening each package.
+func (s *Scanner) SetOptions(opts *PackageOptions) *Scanner {
+	s.opts = opts
+	return s
+}
+
+// SetSuffixes sets file name suffixes of packages found by ScanDir,
+// ".deb" by default.
+func (s *Scanner) SetSuffixes(suffixes ...string) *Scanner {
+	s.suffixes = suffixes
+	return s
+}
+
+// SetProgress sets a callback reporting progress.
+func (s *Scanner) SetProgress(progress ScanProgress) *Scanner {
+	s.progress = progress
+	return s
+}
+
+// Scan reads packages of paths from the channel until it is closed or the
+// context is cancelled. Results are streamed in order of completion and the
+// returned channel is closed when all workers are done. Failing packages
+// are reported as results with an error and do not stop the scan.
+func (s *Scanner) Scan(ctx context.Context, paths <-chan string) <-chan ScanResult {
+	jobs := make(chan ScanResult)
+	go func() {
+		defer close(jobs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case path, ok := <-paths:
+				if !ok || !s.send(ctx, jobs, ScanResult{Path: path}) {
+					return
+				}
+			}
+		}
+	}()
+	return s.run(ctx, jobs)
+}
+
+// ScanDir reads all packages in a directory tree. Errors of walking the
+// tree are reported as results as well.
+func (s *Scanner) ScanDir(ctx context.Context, root string) <-chan ScanResult {
+	jobs := make(chan ScanResult)
+	go func() {
+		defer close(jobs)
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if !s.send(ctx, jobs, ScanResult{Path: path, Err: err}) {
+					return ctx.Err()
+				}
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() || !s.isPackageName(info.Name()) {
+				return nil
+			}
+			if !s.send(ctx, jobs, ScanResult{Path: path}) {
+				return ctx.Err()
+			}
+			return nil
+		})
+	}()
+	return s.run(ctx, jobs)
+}
+
+// Check if a file name has a package suffix
+func (s *Scanner) isPackageName(name string) bool {
+	for _, sfx := range s.suffixes {
+		if strings.HasSuffix(name, sfx) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send a result, unless the context is cancelled
+func (s *Scanner) send(ctx context.Context, results chan<- ScanResult, result ScanResult) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case results <- result:
+		return true
+	}
+}
+
+// Open packages of jobs with the worker pool. Jobs with an error are passed through.
+func (s *Scanner) run(ctx context.Context, jobs <-chan ScanResult) <-chan ScanResult {
+	results := make(chan ScanResult)
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+
+	wg.Add(s.workers)
+	for idx := 0; idx < s.workers; idx++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					continue // drain, the feeder stops on cancellation
+				}
+				if job.Err == nil {
+					job.Package, job.Err = OpenPackageContext(ctx, job.Path, s.opts)
+				}
+				if s.progress != nil {
+					mtx.Lock()
+					done++
+					s.progress(done, job.Path, job.Err)
+					mtx.Unlock()
+				}
+				s.send(ctx, results, job)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
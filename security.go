@@ -0,0 +1,449 @@
+package deb
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Advisory is a security issue of a source package in a release, as
+// recorded by the Debian security tracker, OVAL definitions or DSA list.
+type Advisory struct {
+	id          string
+	source      string
+	fixed       string
+	urgency     string
+	description string
+	aliases     []string
+}
+
+// ID returns the issue identifier, e.g. "CVE-2023-1234" or "DSA-5584-1".
+func (a *Advisory) ID() string {
+	return a.id
+}
+
+// Source returns the affected source package name.
+func (a *Advisory) Source() string {
+	return a.source
+}
+
+// FixedVersion returns the source version fixing the issue, or an empty
+// string if it is not fixed yet.
+func (a *Advisory) FixedVersion() string {
+	return a.fixed
+}
+
+// Urgency returns the urgency assigned by the security team, e.g. "low".
+func (a *Advisory) Urgency() string {
+	return a.urgency
+}
+
+// Description returns the description of the issue.
+func (a *Advisory) Description() string {
+	return a.description
+}
+
+// Aliases returns other identifiers of the issue, e.g. CVEs fixed by a DSA.
+func (a *Advisory) Aliases() []string {
+	return a.aliases
+}
+
+// Affects returns true if a version of the source package is vulnerable.
+func (a *Advisory) Affects(version string) bool {
+	return a.fixed == "" || CompareVersions(version, a.fixed) < 0
+}
+
+// SecurityDB is a local copy of Debian security data for a release,
+// used to match packages against known vulnerabilities offline.
+type SecurityDB struct {
+	release    string
+	advisories map[string][]*Advisory
+	warnings   []error
+}
+
+// NewSecurityDB constructor. The release is a codename, e.g. "bookworm".
+func NewSecurityDB(release string) *SecurityDB {
+	db := new(SecurityDB)
+	db.release = release
+	db.advisories = make(map[string][]*Advisory)
+	db.warnings = make([]error, 0)
+	return db
+}
+
+// Release returns the codename of the release.
+func (db *SecurityDB) Release() string {
+	return db.release
+}
+
+// Warnings returns problems of loaded data, which were skipped.
+func (db *SecurityDB) Warnings() []error {
+	return db.warnings
+}
+
+// Add an advisory, unless the source package already has one of the same ID
+func (db *SecurityDB) add(adv *Advisory) {
+	for _, known := range db.advisories[adv.source] {
+		if known.id == adv.id {
+			if known.fixed == "" {
+				known.fixed = adv.fixed
+			}
+			if known.urgency == "" {
+				known.urgency = adv.urgency
+			}
+			if known.description == "" {
+				known.description = adv.description
+			}
+			return
+		}
+	}
+	db.advisories[adv.source] = append(db.advisories[adv.source], adv)
+}
+
+// Advisories returns advisories of a source package, sorted by ID.
+func (db *SecurityDB) Advisories(source string) []*Advisory {
+	advs := append([]*Advisory{}, db.advisories[source]...)
+	sort.SliceStable(advs, func(i, j int) bool { return advs[i].id < advs[j].id })
+	return advs
+}
+
+// Issue of the security tracker JSON dump
+type trackerIssue struct {
+	Description string `json:"description"`
+	Releases    map[string]struct {
+		Status       string `json:"status"`
+		FixedVersion string `json:"fixed_version"`
+		Urgency      string `json:"urgency"`
+	} `json:"releases"`
+}
+
+// LoadTrackerJSON loads the security tracker JSON dump, as served at
+// https://security-tracker.debian.org/tracker/data/json. Issues not
+// affecting the release are skipped.
+func (db *SecurityDB) LoadTrackerJSON(r io.Reader) error {
+	data := make(map[string]map[string]trackerIssue)
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return fmt.Errorf("security tracker data: %s", err.Error())
+	}
+	for source, issues := range data {
+		for id, issue := range issues {
+			rel, ok := issue.Releases[db.release]
+			if !ok || rel.FixedVersion == "0" || rel.Urgency == "end-of-life" {
+				continue
+			}
+			if rel.Status == "resolved" && rel.FixedVersion == "" {
+				continue
+			}
+			db.add(&Advisory{id: id, source: source, fixed: rel.FixedVersion, urgency: rel.Urgency,
+				description: issue.Description, aliases: make([]string, 0)})
+		}
+	}
+	return nil
+}
+
+// Matches headers of the DSA list, e.g. "[21 Dec 2023] DSA-5584-1 bluez - security update"
+var dsaHeader = regexp.MustCompile(`^\[[^\]]+\]\s+(\S+)\s+(\S+)\s+-\s*(.*)$`)
+
+// LoadDSAList loads the DSA list of the security tracker repository
+// (data/DSA/list). Advisories list the fixed CVEs as aliases. Unexpected
+// lines are skipped with their entries, and recorded as warnings.
+func (db *SecurityDB) LoadDSAList(r io.Reader) error {
+	var id, description string
+	var aliases []string
+	skip := false
+	lineno := 0
+	scn := bufio.NewScanner(r)
+	for scn.Scan() {
+		lineno++
+		line := scn.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, " "):
+			m := dsaHeader.FindStringSubmatch(line)
+			if m == nil {
+				db.warnings = append(db.warnings, &ParseError{File: "DSA list", Line: lineno, Err: "expected '[date] DSA-id package - description'"})
+				skip = true // skip lines of the entry
+				continue
+			}
+			id, description, aliases, skip = m[1], m[3], make([]string, 0), false
+		case strings.HasPrefix(trimmed, "{"):
+			aliases = strings.Fields(strings.Trim(trimmed, "{}"))
+		case strings.HasPrefix(trimmed, "["+db.release+"]"):
+			// [bookworm] - bluez 5.66-1+deb12u1
+			fields := strings.Fields(strings.TrimPrefix(trimmed, "["+db.release+"]"))
+			if skip {
+				continue
+			}
+			if len(fields) < 3 || fields[0] != "-" || id == "" {
+				db.warnings = append(db.warnings, &ParseError{File: "DSA list", Line: lineno, Err: "expected '[release] - package version'"})
+				continue
+			}
+			if fields[2] == "<not-affected>" {
+				continue
+			}
+			fixed := fields[2]
+			if strings.HasPrefix(fixed, "<") {
+				fixed = ""
+			}
+			db.add(&Advisory{id: id, source: fields[1], fixed: fixed, description: description, aliases: aliases})
+		}
+	}
+	return scn.Err()
+}
+
+// Elements of OVAL definitions of Debian, matched by local names
+type ovalDefinitions struct {
+	Definitions []struct {
+		Class    string `xml:"class,attr"`
+		Metadata struct {
+			Title       string `xml:"title"`
+			Description string `xml:"description"`
+			References  []struct {
+				RefID  string `xml:"ref_id,attr"`
+				Source string `xml:"source,attr"`
+			} `xml:"reference"`
+		} `xml:"metadata"`
+		Criteria ovalCriteria `xml:"criteria"`
+	} `xml:"definitions>definition"`
+	Tests []struct {
+		ID     string `xml:"id,attr"`
+		Object struct {
+			Ref string `xml:"object_ref,attr"`
+		} `xml:"object"`
+		State struct {
+			Ref string `xml:"state_ref,attr"`
+		} `xml:"state"`
+	} `xml:"tests>dpkginfo_test"`
+	Objects []struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:"name"`
+	} `xml:"objects>dpkginfo_object"`
+	States []struct {
+		ID  string `xml:"id,attr"`
+		EVR struct {
+			Operation string `xml:"operation,attr"`
+			Value     string `xml:",chardata"`
+		} `xml:"evr"`
+	} `xml:"states>dpkginfo_state"`
+}
+
+type ovalCriteria struct {
+	Criteria  []ovalCriteria `xml:"criteria"`
+	Criterion []struct {
+		TestRef string `xml:"test_ref,attr"`
+	} `xml:"criterion"`
+}
+
+// All test references of criteria
+func (oc *ovalCriteria) testRefs() []string {
+	refs := make([]string, 0)
+	for _, c := range oc.Criterion {
+		refs = append(refs, c.TestRef)
+	}
+	for idx := range oc.Criteria {
+		refs = append(refs, oc.Criteria[idx].testRefs()...)
+	}
+	return refs
+}
+
+// LoadOVAL loads Debian OVAL definitions of the release, e.g.
+// oval-definitions-bookworm.xml. Package tests comparing the version with
+// "less than" give the fixed version.
+func (db *SecurityDB) LoadOVAL(r io.Reader) error {
+	oval := new(ovalDefinitions)
+	if err := xml.NewDecoder(r).Decode(oval); err != nil {
+		return fmt.Errorf("OVAL definitions: %s", err.Error())
+	}
+	objects := make(map[string]string)
+	for _, obj := range oval.Objects {
+		objects[obj.ID] = obj.Name
+	}
+	states := make(map[string]string)
+	for _, st := range oval.States {
+		if st.EVR.Operation == "less than" {
+			states[st.ID] = strings.TrimSpace(st.EVR.Value)
+		}
+	}
+	type pkgTest struct{ name, fixed string }
+	tests := make(map[string]pkgTest)
+	for _, t := range oval.Tests {
+		if name, ok := objects[t.Object.Ref]; ok {
+			tests[t.ID] = pkgTest{name: name, fixed: states[t.State.Ref]}
+		}
+	}
+
+	for _, def := range oval.Definitions {
+		if def.Class != "" && def.Class != "vulnerability" && def.Class != "patch" {
+			continue
+		}
+		id := strings.TrimSpace(def.Metadata.Title)
+		aliases := make([]string, 0)
+		for _, ref := range def.Metadata.References {
+			if ref.RefID != "" && ref.RefID != id {
+				aliases = append(aliases, ref.RefID)
+			}
+		}
+		for _, ref := range def.Criteria.testRefs() {
+			test, ok := tests[ref]
+			if !ok {
+				continue
+			}
+			fixed := test.fixed
+			if strings.HasPrefix(fixed, "0:") {
+				fixed = fixed[2:] // OVAL always has an epoch
+			}
+			db.add(&Advisory{id: id, source: test.name, fixed: fixed,
+				description: strings.TrimSpace(def.Metadata.Description), aliases: aliases})
+		}
+	}
+	return nil
+}
+
+// Vulnerability is an advisory affecting a binary package. Status is "open"
+// if the issue is not fixed yet in the release, or "fixed" if it is fixed
+// in a newer version of the source package.
+type Vulnerability struct {
+	Package       string   `json:"package"`
+	Version       string   `json:"version"`
+	Architecture  string   `json:"architecture"`
+	Source        string   `json:"source"`
+	SourceVersion string   `json:"source_version"`
+	ID            string   `json:"id"`
+	Aliases       []string `json:"aliases,omitempty"`
+	Status        string   `json:"status"`
+	FixedVersion  string   `json:"fixed_version,omitempty"`
+	Urgency       string   `json:"urgency,omitempty"`
+	Description   string   `json:"description,omitempty"`
+}
+
+// Vulnerabilities of a binary package, given its control stanza. The
+// package is matched by its source package name and version.
+func (db *SecurityDB) match(fields fieldSource) []Vulnerability {
+	vulns := make([]Vulnerability, 0)
+	source, version := fields.Field("Package"), fields.Field("Version")
+	if src := fields.Field("Source"); src != "" {
+		parts := strings.Fields(src)
+		source = parts[0]
+		if len(parts) > 1 {
+			version = strings.Trim(strings.Join(parts[1:], ""), "()")
+		}
+	}
+	for _, adv := range db.Advisories(source) {
+		if !adv.Affects(version) {
+			continue
+		}
+		status := "open"
+		if adv.fixed != "" {
+			status = "fixed"
+		}
+		vulns = append(vulns, Vulnerability{
+			Package:       fields.Field("Package"),
+			Version:       fields.Field("Version"),
+			Architecture:  fields.Field("Architecture"),
+			Source:        source,
+			SourceVersion: version,
+			ID:            adv.id,
+			Aliases:       adv.aliases,
+			Status:        status,
+			FixedVersion:  adv.fixed,
+			Urgency:       adv.urgency,
+			Description:   adv.description,
+		})
+	}
+	return vulns
+}
+
+// MatchPackageFile returns vulnerabilities of a package.
+func (db *SecurityDB) MatchPackageFile(pf *PackageFile) *VulnerabilityReport {
+	return &VulnerabilityReport{Release: db.release, Vulnerabilities: db.match(pf.ControlFile())}
+}
+
+// MatchIndex returns vulnerabilities of packages of a repository index.
+func (db *SecurityDB) MatchIndex(pi *PackagesIndex) *VulnerabilityReport {
+	report := &VulnerabilityReport{Release: db.release, Vulnerabilities: make([]Vulnerability, 0)}
+	for _, ie := range pi.Entries() {
+		report.Vulnerabilities = append(report.Vulnerabilities, db.match(ie)...)
+	}
+	return report
+}
+
+// MatchStatusDB returns vulnerabilities of packages installed on a system.
+func (db *SecurityDB) MatchStatusDB(sdb *StatusDB) *VulnerabilityReport {
+	report := &VulnerabilityReport{Release: db.release, Vulnerabilities: make([]Vulnerability, 0)}
+	for _, se := range sdb.Installed() {
+		report.Vulnerabilities = append(report.Vulnerabilities, db.match(se)...)
+	}
+	return report
+}
+
+// VulnerabilityReport lists vulnerabilities of matched packages.
+type VulnerabilityReport struct {
+	Release         string          `json:"release"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Merge adds vulnerabilities of another report.
+func (vr *VulnerabilityReport) Merge(other *VulnerabilityReport) *VulnerabilityReport {
+	vr.Vulnerabilities = append(vr.Vulnerabilities, other.Vulnerabilities...)
+	return vr
+}
+
+// Count returns the number of vulnerabilities of a status, or all of them
+// if the status is empty.
+func (vr *VulnerabilityReport) Count(status string) int {
+	count := 0
+	for _, v := range vr.Vulnerabilities {
+		if status == "" || v.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Sort vulnerabilities by package, architecture and ID
+func (vr *VulnerabilityReport) sort() {
+	sort.SliceStable(vr.Vulnerabilities, func(i, j int) bool {
+		a, b := vr.Vulnerabilities[i], vr.Vulnerabilities[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Architecture != b.Architecture {
+			return a.Architecture < b.Architecture
+		}
+		return a.ID < b.ID
+	})
+}
+
+// WriteJSON writes the report as a JSON object.
+func (vr *VulnerabilityReport) WriteJSON(w io.Writer) error {
+	vr.sort()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vr)
+}
+
+// WriteText writes one line per vulnerability and a summary.
+func (vr *VulnerabilityReport) WriteText(w io.Writer) error {
+	vr.sort()
+	var out strings.Builder
+	for _, v := range vr.Vulnerabilities {
+		line := fmt.Sprintf("%s %s (%s %s): %s %s", v.Package, v.Version, v.Source, v.SourceVersion, v.ID, v.Status)
+		if v.FixedVersion != "" {
+			line += " in " + v.FixedVersion
+		}
+		if v.Urgency != "" {
+			line += ", urgency " + v.Urgency
+		}
+		out.WriteString(line + "\n")
+	}
+	out.WriteString(fmt.Sprintf("Summary: %d vulnerabilities, %d fixed in newer versions, %d open\n",
+		vr.Count(""), vr.Count("fixed"), vr.Count("open")))
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,41 @@
+package deb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadDSAListUnknownLines(t *testing.T) {
+	list := "[21 Dec 2023] DSA-5584-1 bluez - security update\n" +
+		"\t{CVE-2023-45866}\n" +
+		"\t[bookworm] - bluez 5.66-1+deb12u1\n" +
+		"NOTE: moved to the new format\n" +
+		"\t[bookworm] - bogus 1.0-1\n" +
+		"[20 Dec 2023] DSA-5583-1 gst-plugins-bad1.0 - security update\n" +
+		"\t[bookworm] -\n" +
+		"\t[bullseye] - gst-plugins-bad1.0 1.18.4-3+deb11u3\n" +
+		"[19 Dec 2023] DSA-5582-1 thunderbird - security update\n" +
+		"\t[bookworm] - thunderbird 1:115.6.0-1~deb12u1\n"
+	db := NewSecurityDB("bookworm")
+	if err := db.LoadDSAList(strings.NewReader(list)); err != nil {
+		t.Fatal(err)
+	}
+	if advs := db.Advisories("bluez"); len(advs) != 1 || advs[0].FixedVersion() != "5.66-1+deb12u1" || advs[0].Aliases()[0] != "CVE-2023-45866" {
+		t.Errorf("bluez advisories %+v", advs)
+	}
+	if advs := db.Advisories("thunderbird"); len(advs) != 1 || advs[0].ID() != "DSA-5582-1" {
+		t.Errorf("thunderbird advisories %+v", advs)
+	}
+	if advs := db.Advisories("bogus"); len(advs) != 0 {
+		t.Errorf("advisory of an unknown entry: %+v", advs)
+	}
+	warnings := db.Warnings()
+	if len(warnings) != 2 {
+		t.Fatalf("warnings %v", warnings)
+	}
+	for idx, lineno := range []int{4, 7} {
+		if perr, ok := warnings[idx].(*ParseError); !ok || perr.Line != lineno {
+			t.Errorf("warning %v, expected line %d", warnings[idx], lineno)
+		}
+	}
+}
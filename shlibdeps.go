@@ -0,0 +1,206 @@
+package deb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ShlibsResolver computes dependencies of ELF objects in a package on other
+// packages, like dpkg-shlibdeps does. Libraries are looked up in symbols files
+// first and in shlibs files if there are no symbols for them.
+type ShlibsResolver struct {
+	symbols map[string]*SymbolsLibrary
+	shlibs  map[string]*SharedLibrary
+}
+
+// NewShlibsResolver constructor
+func NewShlibsResolver() *ShlibsResolver {
+	r := new(ShlibsResolver)
+	r.symbols = make(map[string]*SymbolsLibrary)
+	r.shlibs = make(map[string]*SharedLibrary)
+	return r
+}
+
+// AddSymbolsFile adds library blocks of a symbols file. Libraries that are
+// already known are not overridden.
+func (r *ShlibsResolver) AddSymbolsFile(sf *SymbolsFile) *ShlibsResolver {
+	for _, lib := range sf.Libraries() {
+		if _, ok := r.symbols[lib.Soname()]; !ok {
+			r.symbols[lib.Soname()] = lib
+		}
+	}
+	return r
+}
+
+// AddSharedLibsFile adds libraries of a shlibs file. Only entries for regular
+// packages are used, the ones tagged for other package types (e.g. udeb) are skipped.
+func (r *ShlibsResolver) AddSharedLibsFile(sf *SharedLibsFile) *ShlibsResolver {
+	for _, shl := range sf.Libraries() {
+		shl := shl
+		key := shl.Name() + " " + shl.Version()
+		if _, ok := r.shlibs[key]; !ok && shl.Tag() == "" {
+			r.shlibs[key] = &shl
+		}
+	}
+	return r
+}
+
+// AddPackageFile adds symbols and shlibs files of a package.
+func (r *ShlibsResolver) AddPackageFile(pf *PackageFile) *ShlibsResolver {
+	return r.AddSymbolsFile(pf.SymbolsFile()).AddSharedLibsFile(pf.SharedLibsFile())
+}
+
+// AddDpkgDatabase adds symbols and shlibs files of installed packages from
+// the dpkg database info directory, usually /var/lib/dpkg/info.
+func (r *ShlibsResolver) AddDpkgDatabase(infodir string) error {
+	for _, ext := range []string{"*.symbols", "*.shlibs"} {
+		paths, err := filepath.Glob(filepath.Join(infodir, ext))
+		if err != nil {
+			return err
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if strings.HasSuffix(path, ".symbols") {
+				sf := NewSymbolsFile()
+				sf.parse(data) // Malformed lines are skipped
+				r.AddSymbolsFile(sf)
+			} else {
+				sf := NewSharedLibsFile()
+				sf.parse(data)
+				r.AddSharedLibsFile(sf)
+			}
+		}
+	}
+	return nil
+}
+
+// SONAMEs with the version after or before the .so suffix
+var (
+	sonameVersionSuffix = regexp.MustCompile(`^(.*)\.so\.(.*)$`)
+	sonameVersionInfix  = regexp.MustCompile(`^(.*)-(\d.*)\.so$`)
+)
+
+// Split SONAME into library name and version as used in shlibs files,
+// e.g. libfoo.so.1 is "libfoo 1" and libfoo-1.2.so is "libfoo 1.2".
+func splitSoname(soname string) (string, string) {
+	if m := sonameVersionSuffix.FindStringSubmatch(soname); m != nil {
+		return m[1], m[2]
+	}
+	if m := sonameVersionInfix.FindStringSubmatch(soname); m != nil {
+		return m[1], m[2]
+	}
+	return soname, ""
+}
+
+// Resolve returns the dependencies of ELF objects in the package, merged to
+// the minimal list with the highest required versions and sorted by name.
+// Libraries shipped in the package itself are skipped.
+//
+// The package must be read with files processed. If some libraries could
+// not be found in any source, an error listing them is returned together
+// with the dependencies that were resolved.
+func (r *ShlibsResolver) Resolve(pf *PackageFile) ([]string, error) {
+	own := make(map[string]bool)
+	for _, ef := range pf.ElfFiles() {
+		if ef.Soname() != "" {
+			own[ef.Soname()] = true
+		}
+	}
+
+	templates := make([]string, 0)
+	unresolved := make([]string, 0)
+	for _, ef := range pf.ElfFiles() {
+		for _, soname := range ef.Needed() {
+			if own[soname] {
+				continue
+			}
+			name, version := splitSoname(soname)
+			if lib, ok := r.symbols[soname]; ok {
+				templates = append(templates, r.symbolsDependencies(lib, &ef)...)
+			} else if shl, ok := r.shlibs[name+" "+version]; ok {
+				templates = append(templates, strings.Join(shl.Dependencies(), ", "))
+			} else if !in(soname, unresolved) {
+				unresolved = append(unresolved, soname)
+			}
+		}
+	}
+
+	deps, err := mergeDependencies(templates, pf.ControlFile().Package())
+	if err != nil {
+		return nil, err
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return deps, fmt.Errorf("no dependency information found for %s", strings.Join(unresolved, ", "))
+	}
+	return deps, nil
+}
+
+// Dependencies required by symbols an ELF object uses from a library
+func (r *ShlibsResolver) symbolsDependencies(lib *SymbolsLibrary, ef *ElfFile) []string {
+	deps := make([]string, 0)
+	for _, sym := range ef.Symbols() {
+		if sym.Library() != "" && sym.Library() != lib.Soname() {
+			continue
+		}
+		if se := lib.Lookup(sym.Name(), sym.Version()); se != nil {
+			deps = append(deps, lib.DependencyFor(se))
+		}
+	}
+	if len(deps) == 0 {
+		// Linked, but no symbols used: depend on the library without a version
+		deps = append(deps, lib.DependencyFor(NewSymbolElement()))
+	}
+	return deps
+}
+
+// Merge dependency templates, keeping the highest version for each simple
+// ">=" relation and dropping relations to the package itself.
+func mergeDependencies(templates []string, self string) ([]string, error) {
+	versions := make(map[string]string)
+	others := make(map[string]bool)
+	for _, tpl := range templates {
+		groups, err := ParseRelations(tpl)
+		if err != nil {
+			return nil, err
+		}
+		for _, grp := range groups {
+			alts := grp.Alternatives()
+			if len(alts) == 1 && (alts[0].Operator() == "" || alts[0].Operator() == ">=") {
+				if alts[0].Name() == self {
+					continue
+				}
+				name := alts[0].Name()
+				if alts[0].ArchQualifier() != "" {
+					name += ":" + alts[0].ArchQualifier()
+				}
+				if current, ok := versions[name]; !ok || (alts[0].Version() != "" && (current == "" || CompareVersions(alts[0].Version(), current) > 0)) {
+					versions[name] = alts[0].Version()
+				}
+			} else {
+				others[grp.String()] = true
+			}
+		}
+	}
+
+	deps := make([]string, 0, len(versions)+len(others))
+	for name, version := range versions {
+		if version != "" {
+			name += " (>= " + version + ")"
+		}
+		deps = append(deps, name)
+	}
+	for dep := range others {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
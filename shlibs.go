@@ -2,29 +2,36 @@ package deb
 
 import (
 	"bufio"
-	"regexp"
+	"bytes"
+	"fmt"
+	"io"
 	"strings"
 )
 
+// SharedLibrary is an entry of a shlibs file, see deb-shlibs(5):
+//
+//	[type:] library-name soname-version dependencies
 type SharedLibrary struct {
 	tag          string
 	library      string
 	version      string
-	dependencies []string
+	dependencies []RelationGroup
 }
 
 func NewSharedLibrary() *SharedLibrary {
 	shl := new(SharedLibrary)
-	shl.dependencies = make([]string, 0)
+	shl.dependencies = make([]RelationGroup, 0)
 
 	return shl
 }
 
 type SharedLibsFile struct {
 	libraries []SharedLibrary
+	errors    []error
 }
 
-// Tag returns possible shared library tag. Usually it is an empty string.
+// Tag returns the package type the entry is for, e.g. "udeb", without the
+// trailing colon. Usually it is an empty string, meaning regular packages.
 func (shl *SharedLibrary) Tag() string {
 	return shl.tag
 }
@@ -40,40 +47,110 @@ func (shl *SharedLibrary) Version() string {
 }
 
 // Dependencies returns the list of dependencies of the shared library.
+// Alternatives are kept within one element, e.g. "libfoo1 | libfoo1-alt".
 func (shl *SharedLibrary) Dependencies() []string {
+	deps := make([]string, 0, len(shl.dependencies))
+	for idx := range shl.dependencies {
+		deps = append(deps, shl.dependencies[idx].String())
+	}
+	return deps
+}
+
+// Relations returns parsed dependencies of the shared library.
+func (shl *SharedLibrary) Relations() []RelationGroup {
 	return shl.dependencies
 }
 
+// SetTag sets the package type of the entry, e.g. "udeb".
+func (shl *SharedLibrary) SetTag(tag string) *SharedLibrary {
+	shl.tag = strings.TrimSuffix(tag, ":")
+	return shl
+}
+
+// SetName sets the name of the shared library, e.g. "libfoo" for libfoo.so.1
+func (shl *SharedLibrary) SetName(name string) *SharedLibrary {
+	shl.library = name
+	return shl
+}
+
+// SetVersion sets the SONAME version of the shared library, e.g. "1" for libfoo.so.1
+func (shl *SharedLibrary) SetVersion(version string) *SharedLibrary {
+	shl.version = version
+	return shl
+}
+
+// SetDependencies parses and sets dependencies, e.g. "libfoo1 (>= 1.2)".
+func (shl *SharedLibrary) SetDependencies(deps string) error {
+	groups, err := ParseRelations(deps)
+	if err != nil {
+		return err
+	}
+	shl.dependencies = groups
+	return nil
+}
+
+// String returns the entry as a shlibs file line.
+func (shl *SharedLibrary) String() string {
+	line := strings.Join([]string{shl.library, shl.version, strings.Join(shl.Dependencies(), ", ")}, " ")
+	if shl.tag != "" {
+		line = shl.tag + ": " + line
+	}
+	return line
+}
+
+// Parse a shlibs line
+func (shl *SharedLibrary) parse(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+		shl.SetTag(fields[0])
+		fields = fields[1:]
+		if shl.tag == "" {
+			return fmt.Errorf("empty package type")
+		}
+	}
+
+	switch len(fields) {
+	case 0:
+		return fmt.Errorf("missing library name")
+	case 1:
+		return fmt.Errorf("missing version of library '%s'", fields[0])
+	case 2:
+		return fmt.Errorf("missing dependencies of library '%s'", fields[0])
+	}
+	shl.library, shl.version = fields[0], fields[1]
+
+	return shl.SetDependencies(strings.Join(fields[2:], " "))
+}
+
 func NewSharedLibsFile() *SharedLibsFile {
 	shl := new(SharedLibsFile)
 	shl.libraries = make([]SharedLibrary, 0)
+	shl.errors = make([]error, 0)
 
 	return shl
 }
 
+// Parse shlibs data. Malformed lines are skipped and collected as errors,
+// the first of them is returned.
 func (shlf *SharedLibsFile) parse(data []byte) error {
-	var line string
+	lineno := 0
 	scn := bufio.NewScanner(strings.NewReader(string(data)))
 	for scn.Scan() {
-		line = strings.TrimSpace(scn.Text())
+		lineno++
+		line := strings.TrimSpace(scn.Text())
 		if line != "" && !strings.HasPrefix(line, "#") {
 			shl := NewSharedLibrary()
-			fe := strings.SplitN(line, " ", 2)
-			if strings.HasSuffix(fe[0], ":") {
-				shl.tag = fe[0]
-				line = fe[1]
-			}
-
-			fe = strings.SplitN(line, " ", 3)
-			shl.library, shl.version = fe[0], fe[1]
-			fe = regexp.MustCompile(`[\\,\\|]`).Split(fe[2], -1)
-			for _, v := range fe {
-				shl.dependencies = append(shl.dependencies, strings.TrimSpace(v))
+			if err := shl.parse(line); err != nil {
+				shlf.errors = append(shlf.errors, &ParseError{File: "shlibs", Line: lineno, Err: err.Error()})
+				continue
 			}
 			shlf.libraries = append(shlf.libraries, *shl)
 		}
 	}
 
+	if len(shlf.errors) > 0 {
+		return shlf.errors[0]
+	}
 	return nil
 }
 
@@ -81,3 +158,30 @@ func (shlf *SharedLibsFile) parse(data []byte) error {
 func (shl *SharedLibsFile) Libraries() []SharedLibrary {
 	return shl.libraries
 }
+
+// Errors returns syntax errors found while parsing.
+func (shl *SharedLibsFile) Errors() []error {
+	return shl.errors
+}
+
+// AddLibrary adds an entry to the shlibs file.
+func (shl *SharedLibsFile) AddLibrary(lib *SharedLibrary) *SharedLibsFile {
+	shl.libraries = append(shl.libraries, *lib)
+	return shl
+}
+
+// WriteTo writes the shlibs file in the deb-shlibs(5) format.
+func (shl *SharedLibsFile) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for idx := range shl.libraries {
+		buf.WriteString(shl.libraries[idx].String() + "\n")
+	}
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the content of the shlibs file.
+func (shl *SharedLibsFile) Bytes() []byte {
+	var buf bytes.Buffer
+	shl.WriteTo(&buf) // Writing to a buffer does not fail
+	return buf.Bytes()
+}
@@ -0,0 +1,143 @@
+package deb
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// ReadSigningKey reads the first secret key of an armored OpenPGP keyring,
+// such as exported with "gpg --export-secret-keys --armor". Protected keys
+// are decrypted with the passphrase.
+func ReadSigningKey(keyring io.Reader, passphrase []byte) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(keyring)
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		keys := []*packet.PrivateKey{entity.PrivateKey}
+		for _, subkey := range entity.Subkeys {
+			if subkey.PrivateKey != nil {
+				keys = append(keys, subkey.PrivateKey)
+			}
+		}
+		for _, key := range keys {
+			if !key.Encrypted {
+				continue
+			}
+			if len(passphrase) == 0 {
+				return nil, fmt.Errorf("secret key %s is protected with a passphrase", key.KeyIdString())
+			}
+			if err := key.Decrypt(passphrase); err != nil {
+				return nil, fmt.Errorf("can not decrypt secret key %s: %s", key.KeyIdString(), err.Error())
+			}
+		}
+		return entity, nil
+	}
+	return nil, fmt.Errorf("keyring has no secret key")
+}
+
+// Key used for signing at a time: the newest signing subkey, or the primary
+// key. Expired and revoked keys are not used, nor are subkeys of an expired
+// or revoked primary key.
+func signingKey(entity *openpgp.Entity, now time.Time) (*packet.PrivateKey, error) {
+	key, ok := entity.SigningKey(now)
+	if !ok || key.PrivateKey == nil {
+		return nil, fmt.Errorf("key %s has no valid signing key, it may be expired or revoked", entity.PrimaryKey.KeyIdString())
+	}
+	return key.PrivateKey, nil
+}
+
+// SignRelease signs a Release file, returning an armored detached signature
+// for Release.gpg and an inline-signed InRelease file. The signing key must
+// be valid now.
+func SignRelease(release []byte, signer *openpgp.Entity) ([]byte, []byte, error) {
+	config := &packet.Config{DefaultHash: crypto.SHA256, Time: time.Now}
+	key, err := signingKey(signer, config.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	config.SigningKeyId = key.KeyId
+
+	var detached bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&detached, signer, bytes.NewReader(release), config); err != nil {
+		return nil, nil, err
+	}
+	detached.WriteString("\n")
+
+	var inline bytes.Buffer
+	w, err := clearsign.Encode(&inline, key, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := w.Write(release); err != nil {
+		return nil, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+	inline.WriteString("\n")
+	return detached.Bytes(), inline.Bytes(), nil
+}
+
+// Read a keyring of public keys, armored or binary
+func readKeyRing(keyring io.Reader) (openpgp.EntityList, error) {
+	data, err := ioutil.ReadAll(keyring)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %s", err.Error())
+	}
+	return entities, nil
+}
+
+// VerifyRelease checks a detached signature of a Release file, as in
+// Release.gpg, with a keyring of trusted keys, such as one of
+// /etc/apt/trusted.gpg.d. It returns the key owner who signed it.
+func VerifyRelease(release []byte, signature []byte, keyring io.Reader) (*openpgp.Entity, error) {
+	keys, err := readKeyRing(keyring)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := openpgp.CheckArmoredDetachedSignature(keys, bytes.NewReader(release), bytes.NewReader(signature), nil)
+	if err != nil && !bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		signer, err = openpgp.CheckDetachedSignature(keys, bytes.NewReader(release), bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Release signature: %s", err.Error())
+	}
+	return signer, nil
+}
+
+// VerifyInRelease checks an inline-signed InRelease file with a keyring of
+// trusted keys. It returns the signed Release content and the key owner.
+func VerifyInRelease(inRelease []byte, keyring io.Reader) ([]byte, *openpgp.Entity, error) {
+	keys, err := readKeyRing(keyring)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := clearsign.Decode(inRelease)
+	if block == nil {
+		return nil, nil, fmt.Errorf("InRelease is not a signed message")
+	}
+	signer, err := block.VerifySignature(keys, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("InRelease signature: %s", err.Error())
+	}
+	return block.Plaintext, signer, nil
+}
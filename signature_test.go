@@ -0,0 +1,149 @@
+package deb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// Throwaway key created at a time, valid for a lifetime if not zero
+func testKey(t *testing.T, created time.Time, lifetime time.Duration) *openpgp.Entity {
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA, Time: func() time.Time { return created },
+		KeyLifetimeSecs: uint32(lifetime / time.Second)}
+	entity, err := openpgp.NewEntity("Test Archive", "", "archive@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+// Armored secret keyring of a key, protected with a passphrase if not empty
+func testSecretKeyring(t *testing.T, entity *openpgp.Entity, passphrase []byte) []byte {
+	if len(passphrase) > 0 {
+		// Serialize a copy, so the key stays usable for signing
+		var buf bytes.Buffer
+		if err := entity.SerializePrivate(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		entities, err := openpgp.ReadKeyRing(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entity = entities[0]
+		if err := entity.EncryptPrivateKeys(passphrase, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// Binary public keyring of a key
+func testPublicKeyring(t *testing.T, entity *openpgp.Entity) []byte {
+	var buf bytes.Buffer
+	if err := entity.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSignRelease(t *testing.T) {
+	key := testKey(t, time.Now().Add(-time.Hour), 0)
+	other := testKey(t, time.Now().Add(-time.Hour), 0)
+	release := []byte("Origin: Test\nSuite: stable\nDate: Mon, 19 Oct 2026 00:00:00 UTC\n")
+	detached, inline, err := SignRelease(release, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyring []byte
+		release []byte
+		err     string
+	}{
+		{"good signature", testPublicKeyring(t, key), release, ""},
+		{"wrong key", testPublicKeyring(t, other), release, "signature"},
+		{"keyring of both keys", append(testPublicKeyring(t, other), testPublicKeyring(t, key)...), release, ""},
+		{"modified release", testPublicKeyring(t, key), append([]byte("Label: Evil\n"), release...), "signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := VerifyRelease(tt.release, detached, bytes.NewReader(tt.keyring))
+			checkSignError(t, "Release.gpg", err, tt.err)
+			if err == nil && signer.PrimaryKey.KeyId != key.PrimaryKey.KeyId {
+				t.Errorf("Release.gpg signed by %s", signer.PrimaryKey.KeyIdString())
+			}
+			if !bytes.Equal(tt.release, release) {
+				// An InRelease file carries its content
+				return
+			}
+			content, signer, err := VerifyInRelease(inline, bytes.NewReader(tt.keyring))
+			checkSignError(t, "InRelease", err, tt.err)
+			if err == nil && (signer.PrimaryKey.KeyId != key.PrimaryKey.KeyId || !bytes.Equal(content, release)) {
+				t.Errorf("InRelease signed by %s with content %q", signer.PrimaryKey.KeyIdString(), content)
+			}
+		})
+	}
+	if _, _, err := VerifyInRelease(release, bytes.NewReader(testPublicKeyring(t, key))); err == nil {
+		t.Errorf("unsigned InRelease verified")
+	}
+}
+
+// Check an error of a verification, expecting one containing a substring
+func checkSignError(t *testing.T, name string, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Errorf("%s: %v", name, err)
+	} else if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Errorf("%s: error %v, expected %q", name, err, expected)
+	}
+}
+
+func TestSignReleaseExpiredKey(t *testing.T) {
+	key := testKey(t, time.Now().Add(-2*time.Hour), time.Hour)
+	if _, _, err := SignRelease([]byte("Suite: stable\n"), key); err == nil || !strings.Contains(err.Error(), "no valid signing key") {
+		t.Errorf("signed with an expired key: %v", err)
+	}
+}
+
+func TestReadSigningKey(t *testing.T) {
+	key := testKey(t, time.Now().Add(-time.Hour), 0)
+	passphrase := []byte("secret")
+	protected := testSecretKeyring(t, key, passphrase)
+
+	if _, err := ReadSigningKey(bytes.NewReader(protected), nil); err == nil || !strings.Contains(err.Error(), "passphrase") {
+		t.Errorf("protected key read without a passphrase: %v", err)
+	}
+	if _, err := ReadSigningKey(bytes.NewReader(protected), []byte("wrong")); err == nil {
+		t.Errorf("protected key read with a wrong passphrase")
+	}
+	signer, err := ReadSigningKey(bytes.NewReader(protected), passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := SignRelease([]byte("Suite: stable\n"), signer); err != nil {
+		t.Errorf("sign with a decrypted key: %v", err)
+	}
+
+	var public bytes.Buffer
+	w, _ := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	key.Serialize(w)
+	w.Close()
+	if _, err := ReadSigningKey(&public, nil); err == nil {
+		t.Errorf("signing key read from a public keyring")
+	}
+}
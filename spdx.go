@@ -0,0 +1,186 @@
+package deb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Short license names of the machine-readable copyright format mapped to SPDX
+// identifiers. Versioned GNU licenses are handled separately.
+var spdxLicenses = map[string]string{
+	"public-domain":   "LicenseRef-public-domain",
+	"apache-1.0":      "Apache-1.0",
+	"apache-1.1":      "Apache-1.1",
+	"apache-2.0":      "Apache-2.0",
+	"artistic":        "Artistic-1.0",
+	"artistic-1.0":    "Artistic-1.0",
+	"artistic-2.0":    "Artistic-2.0",
+	"bsd-2-clause":    "BSD-2-Clause",
+	"bsd-3-clause":    "BSD-3-Clause",
+	"bsd-4-clause":    "BSD-4-Clause",
+	"bsl-1.0":         "BSL-1.0",
+	"cc-by-1.0":       "CC-BY-1.0",
+	"cc-by-2.0":       "CC-BY-2.0",
+	"cc-by-2.5":       "CC-BY-2.5",
+	"cc-by-3.0":       "CC-BY-3.0",
+	"cc-by-4.0":       "CC-BY-4.0",
+	"cc-by-sa-1.0":    "CC-BY-SA-1.0",
+	"cc-by-sa-2.0":    "CC-BY-SA-2.0",
+	"cc-by-sa-2.5":    "CC-BY-SA-2.5",
+	"cc-by-sa-3.0":    "CC-BY-SA-3.0",
+	"cc-by-sa-4.0":    "CC-BY-SA-4.0",
+	"cc0-1.0":         "CC0-1.0",
+	"cddl-1.0":        "CDDL-1.0",
+	"cpl-1.0":         "CPL-1.0",
+	"efl-1":           "EFL-1.0",
+	"efl-2":           "EFL-2.0",
+	"epl-1.0":         "EPL-1.0",
+	"epl-2.0":         "EPL-2.0",
+	"expat":           "MIT",
+	"mit":             "MIT",
+	"isc":             "ISC",
+	"lppl-1.0":        "LPPL-1.0",
+	"lppl-1.1":        "LPPL-1.1",
+	"lppl-1.2":        "LPPL-1.2",
+	"lppl-1.3c":       "LPPL-1.3c",
+	"mpl-1.0":         "MPL-1.0",
+	"mpl-1.1":         "MPL-1.1",
+	"mpl-2.0":         "MPL-2.0",
+	"ofl-1.0":         "OFL-1.0",
+	"ofl-1.1":         "OFL-1.1",
+	"openssl":         "OpenSSL",
+	"perl":            "(Artistic-1.0-Perl OR GPL-1.0-or-later)",
+	"psf-2":           "PSF-2.0",
+	"python-2.0":      "Python-2.0",
+	"python-cnri-1.0": "CNRI-Python",
+	"qpl-1.0":         "QPL-1.0",
+	"unlicense":       "Unlicense",
+	"w3c":             "W3C",
+	"wtfpl":           "WTFPL",
+	"zlib":            "Zlib",
+	"zope-1.1":        "ZPL-1.1",
+	"zope-2.0":        "ZPL-2.0",
+	"zope-2.1":        "ZPL-2.1",
+}
+
+// License exceptions, as used after "with", mapped to SPDX exception identifiers.
+var spdxExceptions = map[string]string{
+	"autoconf":  "Autoconf-exception-2.0",
+	"bison":     "Bison-exception-2.2",
+	"classpath": "Classpath-exception-2.0",
+	"font":      "Font-exception-2.0",
+	"gcc":       "GCC-exception-3.1",
+	"libtool":   "Libtool-exception",
+	"qt":        "Qt-LGPL-exception-1.1",
+}
+
+var gnuLicense = regexp.MustCompile(`(?i)^(AGPL|LGPL|GPL|GFDL|GFDL-NIV)-([0-9](?:\.[0-9])?)(\+)?$`)
+
+// Characters not allowed in a LicenseRef
+var licenseRefInvalid = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// NormalizeLicense converts a license short name or expression from a
+// machine-readable copyright file into an SPDX license expression, e.g.
+// "GPL-2+ or Artistic, and Expat" becomes
+// "(GPL-2.0-or-later OR Artistic-1.0) AND MIT".
+//
+// Licenses without an SPDX identifier are returned as "LicenseRef-" references.
+func NormalizeLicense(expr string) string {
+	var out string
+	parts := strings.Split(strings.TrimSpace(expr), ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		op := "AND"
+		lower := strings.ToLower(part)
+		if strings.HasPrefix(lower, "and ") {
+			part = strings.TrimSpace(part[4:])
+		} else if strings.HasPrefix(lower, "or ") {
+			op, part = "OR", strings.TrimSpace(part[3:])
+		}
+		if part == "" {
+			continue
+		}
+		sub := normalizeLicenseTerms(part)
+		if len(parts) > 1 && strings.Contains(sub, " ") {
+			sub = "(" + sub + ")"
+		}
+		if i == 0 || out == "" {
+			out = sub
+		} else {
+			out += " " + op + " " + sub
+		}
+	}
+	return out
+}
+
+// Normalize expression without commas
+func normalizeLicenseTerms(expr string) string {
+	var terms []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			terms = append(terms, normalizeLicenseTerm(strings.Join(current, " ")))
+			current = nil
+		}
+	}
+	for _, word := range strings.Fields(expr) {
+		switch strings.ToLower(word) {
+		case "or", "and":
+			flush()
+			terms = append(terms, strings.ToUpper(word))
+		default:
+			current = append(current, word)
+		}
+	}
+	flush()
+	return strings.Join(terms, " ")
+}
+
+// Normalize a single license name, optionally followed by an exception.
+func normalizeLicenseTerm(term string) string {
+	name, exception := term, ""
+	if idx := strings.Index(strings.ToLower(term), " with "); idx > -1 {
+		name, exception = strings.TrimSpace(term[:idx]), strings.TrimSpace(term[idx+6:])
+	}
+
+	id := spdxLicenseID(name)
+	if exception == "" {
+		return id
+	}
+	exName := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(exception), "exception"))
+	if ex, ok := spdxExceptions[exName]; ok && !strings.HasPrefix(id, "LicenseRef-") {
+		return id + " WITH " + ex
+	}
+	return licenseRef(term)
+}
+
+// Return SPDX identifier of a license short name
+func spdxLicenseID(name string) string {
+	if id, ok := spdxLicenses[strings.ToLower(name)]; ok {
+		return id
+	}
+
+	if m := gnuLicense.FindStringSubmatch(name); m != nil {
+		family, version := strings.ToUpper(m[1]), m[2]
+		if !strings.Contains(version, ".") {
+			version += ".0"
+		}
+		suffix := "-only"
+		if m[3] == "+" {
+			suffix = "-or-later"
+		}
+		if family == "GFDL-NIV" {
+			return "GFDL-" + version + "-no-invariants" + suffix
+		}
+		return family + "-" + version + suffix
+	}
+
+	return licenseRef(name)
+}
+
+// Make a LicenseRef out of any license name
+func licenseRef(name string) string {
+	ref := strings.Replace(strings.TrimSpace(name), "+", "plus", -1)
+	ref = licenseRefInvalid.ReplaceAllString(ref, "-")
+	return "LicenseRef-" + strings.Trim(ref, "-")
+}
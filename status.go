@@ -0,0 +1,194 @@
+package deb
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default locations of the status databases of dpkg and opkg, and of their
+// directories with files lists, scripts and checksums of installed packages
+const (
+	DPKG_STATUS_PATH = "/var/lib/dpkg/status"
+	DPKG_INFO_DIR    = "/var/lib/dpkg/info"
+	OPKG_STATUS_PATH = "/var/lib/opkg/status"
+	OPKG_INFO_DIR    = "/usr/lib/opkg/info"
+)
+
+// StatusEntry is a package stanza of the dpkg status database.
+type StatusEntry struct {
+	fields *Paragraph
+}
+
+// Package returns the package name
+func (se *StatusEntry) Package() string {
+	return se.fields.Get("Package")
+}
+
+// Version returns the package version
+func (se *StatusEntry) Version() string {
+	return se.fields.Get("Version")
+}
+
+// Architecture returns the package architecture
+func (se *StatusEntry) Architecture() string {
+	return se.fields.Get("Architecture")
+}
+
+// Source returns the source package name. It is the binary package name
+// if the Source field is missing.
+func (se *StatusEntry) Source() string {
+	if source := strings.Fields(se.fields.Get("Source")); len(source) > 0 {
+		return source[0]
+	}
+	return se.Package()
+}
+
+// SourceVersion returns the source package version, if it differs from
+// the binary package version, or the package version otherwise.
+func (se *StatusEntry) SourceVersion() string {
+	source := se.fields.Get("Source")
+	if idx := strings.Index(source, "("); idx > -1 {
+		return strings.TrimSpace(strings.Trim(source[idx:], "()"))
+	}
+	return se.Version()
+}
+
+// Status returns the want, error flag and status words, e.g.
+// "install ok installed".
+func (se *StatusEntry) Status() []string {
+	return strings.Fields(se.fields.Get("Status"))
+}
+
+// IsInstalled returns true if the package is installed and configured.
+func (se *StatusEntry) IsInstalled() bool {
+	status := se.Status()
+	return len(status) == 3 && status[2] == "installed"
+}
+
+// InstalledTime returns the time of installation recorded by opkg, or zero
+// time if it is unknown.
+func (se *StatusEntry) InstalledTime() time.Time {
+	if sec, err := strconv.ParseInt(se.fields.Get("Installed-Time"), 10, 64); err == nil {
+		return time.Unix(sec, 0)
+	}
+	return time.Time{}
+}
+
+// IsAutoInstalled returns true if opkg installed the package as a dependency.
+func (se *StatusEntry) IsAutoInstalled() bool {
+	return se.fields.Get("Auto-Installed") == "yes"
+}
+
+// Path of a file of the package in an info directory, e.g. "list"
+func (se *StatusEntry) infoPath(infoDir string, ext string) string {
+	path := filepath.Join(infoDir, se.Package()+":"+se.Architecture()+"."+ext)
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(infoDir, se.Package()+"."+ext)
+	}
+	return path
+}
+
+// InfoFiles returns paths of files installed by the package, listed in the
+// info directory of dpkg or opkg, e.g. DPKG_INFO_DIR.
+func (se *StatusEntry) InfoFiles(infoDir string) ([]string, error) {
+	data, err := ioutil.ReadFile(se.infoPath(infoDir, "list"))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		// opkg may add the mode and link target, separated by tabs
+		if line = strings.SplitN(line, "\t", 2)[0]; strings.TrimSpace(line) != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// Field returns the value of any field. Field names are case-insensitive.
+func (se *StatusEntry) Field(name string) string {
+	return se.fields.Get(name)
+}
+
+// Fields returns field names in order of appearance.
+func (se *StatusEntry) Fields() []string {
+	return se.fields.Names()
+}
+
+// StatusDB is the status database of dpkg or opkg, listing known packages.
+type StatusDB struct {
+	entries []*StatusEntry
+}
+
+// NewStatusDB constructor
+func NewStatusDB() *StatusDB {
+	db := new(StatusDB)
+	db.entries = make([]*StatusEntry, 0)
+	return db
+}
+
+// ParseStatusDB reads a dpkg status database.
+func ParseStatusDB(r io.Reader) (*StatusDB, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	paragraphs, err := parseParagraphs(data)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			perr.File = "status"
+		}
+		return nil, err
+	}
+	db := NewStatusDB()
+	for idx, p := range paragraphs {
+		if p.Get("Package") == "" {
+			return nil, fmt.Errorf("status: stanza %d has no Package field", idx+1)
+		}
+		db.entries = append(db.entries, &StatusEntry{fields: p})
+	}
+	return db, nil
+}
+
+// OpenStatusDB reads a status database file, usually DPKG_STATUS_PATH or
+// OPKG_STATUS_PATH.
+func OpenStatusDB(path string) (*StatusDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseStatusDB(f)
+}
+
+// Entries returns all entries, including removed packages with kept configuration.
+func (db *StatusDB) Entries() []*StatusEntry {
+	return db.entries
+}
+
+// Installed returns entries of installed packages.
+func (db *StatusDB) Installed() []*StatusEntry {
+	installed := make([]*StatusEntry, 0)
+	for _, se := range db.entries {
+		if se.IsInstalled() {
+			installed = append(installed, se)
+		}
+	}
+	return installed
+}
+
+// Get returns the entry of a package, or nil. Empty architecture matches any.
+func (db *StatusDB) Get(name string, arch string) *StatusEntry {
+	for _, se := range db.entries {
+		if se.Package() == name && (arch == "" || se.Architecture() == arch) {
+			return se
+		}
+	}
+	return nil
+}
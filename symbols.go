@@ -2,64 +2,420 @@ package deb
 
 import (
 	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
-// Basic symbol description. It is not parsed "to the ground" at the moment.
-// It has only a symbol itself and the version of it.
+// SymbolElement is a symbol line of a symbols file, see deb-symbols(5):
+//
+//	[(tag|tag=value|...)]symbol@version minimal-version [dependency-template-id]
 type SymbolElement struct {
-	base    string
-	version string
+	base     string
+	name     string
+	version  string
+	depIndex int
+	tags     map[string]string
+	tagNames []string
+	regex    *regexp.Regexp
 }
 
 // NewSymbolElement constuctor.
 func NewSymbolElement() *SymbolElement {
 	se := new(SymbolElement)
+	se.tags = make(map[string]string)
+	se.tagNames = make([]string, 0)
 	return se
 }
 
-// Returns the entire symbol in one row
-// This does not parses (yet?) things like:
-// (arch-bits=32|arch-endian=little)32bit_le_symbol@Base etc.
+// Returns the entire symbol specification as written, including tags, e.g.
+// (arch-bits=32|arch-endian=little)32bit_le_symbol@Base
 func (se *SymbolElement) Base() string {
 	return se.base
 }
 
-// Returns version of the symbol
+// Name returns the symbol with its version and without tags, e.g. "foo@Base".
+func (se *SymbolElement) Name() string {
+	return se.name
+}
+
+// SymbolName returns the symbol name without the version part.
+func (se *SymbolElement) SymbolName() string {
+	if idx := strings.LastIndex(se.name, "@"); idx > -1 {
+		return se.name[:idx]
+	}
+	return se.name
+}
+
+// SymbolVersion returns the symbol version, e.g. "Base" or "GLIBC_2.2.5".
+func (se *SymbolElement) SymbolVersion() string {
+	if idx := strings.LastIndex(se.name, "@"); idx > -1 {
+		return se.name[idx+1:]
+	}
+	return ""
+}
+
+// Returns minimal version of the package that provides the symbol
 func (se *SymbolElement) Version() string {
 	return se.version
 }
 
+// DependencyIndex returns the dependency template ID of the symbol.
+// Zero refers to the main dependency template of the library.
+func (se *SymbolElement) DependencyIndex() int {
+	return se.depIndex
+}
+
+// Tags returns tag names in order of appearance.
+func (se *SymbolElement) Tags() []string {
+	return se.tagNames
+}
+
+// Tag returns value of a tag. Tags without a value return an empty string.
+func (se *SymbolElement) Tag(name string) string {
+	return se.tags[name]
+}
+
+// HasTag returns true if the symbol is tagged with the given name.
+func (se *SymbolElement) HasTag(name string) bool {
+	_, ok := se.tags[name]
+	return ok
+}
+
+// IsOptional returns true if the symbol may disappear without breaking the ABI.
+func (se *SymbolElement) IsOptional() bool {
+	return se.HasTag("optional")
+}
+
+// IsRegex returns true if the symbol name is a regular expression.
+func (se *SymbolElement) IsRegex() bool {
+	return se.HasTag("regex")
+}
+
+// IsCpp returns true if the symbol name is a demangled C++ symbol.
+func (se *SymbolElement) IsCpp() bool {
+	return se.HasTag("c++")
+}
+
+// Arch returns architecture restrictions of the symbol, if any.
+func (se *SymbolElement) Arch() []string {
+	return strings.Fields(se.tags["arch"])
+}
+
+// Matches returns true if an ELF symbol name and version matches this element.
+// Demangled C++ symbols can not be matched against mangled names and never match.
+func (se *SymbolElement) Matches(name string, version string) bool {
+	if version == "" {
+		version = "Base"
+	}
+	full := name + "@" + version
+	switch {
+	case se.IsCpp():
+		return false
+	case se.IsRegex():
+		return se.regex != nil && se.regex.MatchString(full)
+	default:
+		return se.name == full
+	}
+}
+
+// Parse symbol line, which is already trimmed
+func (se *SymbolElement) parse(line string) error {
+	spec := line
+	if strings.HasPrefix(spec, "(") {
+		end := strings.Index(spec, ")")
+		if end < 0 {
+			return fmt.Errorf("unterminated tag list")
+		}
+		for _, tag := range strings.Split(spec[1:end], "|") {
+			nv := strings.SplitN(tag, "=", 2)
+			name := strings.TrimSpace(nv[0])
+			if name == "" {
+				continue
+			}
+			if _, ok := se.tags[name]; !ok {
+				se.tagNames = append(se.tagNames, name)
+			}
+			if len(nv) == 2 {
+				se.tags[name] = strings.TrimSpace(nv[1])
+			} else {
+				se.tags[name] = ""
+			}
+		}
+		spec = spec[end+1:]
+	}
+
+	var rest string
+	if strings.HasPrefix(spec, "\"") {
+		end := strings.Index(spec[1:], "\"")
+		if end < 0 {
+			return fmt.Errorf("unterminated quoted symbol")
+		}
+		se.name, rest = spec[1:end+1], spec[end+2:]
+	} else {
+		nr := strings.SplitN(spec, " ", 2)
+		se.name = nr[0]
+		if len(nr) == 2 {
+			rest = nr[1]
+		}
+	}
+	if se.name == "" {
+		return fmt.Errorf("missing symbol name")
+	}
+	se.base = strings.TrimSpace(line[:len(line)-len(rest)])
+	if se.IsRegex() {
+		se.regex, _ = regexp.Compile(se.name) // invalid patterns match nothing
+	}
+
+	fields := strings.Fields(rest)
+	switch len(fields) {
+	case 2:
+		id, err := strconv.Atoi(fields[1])
+		if err != nil || id < 0 {
+			return fmt.Errorf("invalid dependency template ID '%s'", fields[1])
+		}
+		se.depIndex = id
+		fallthrough
+	case 1:
+		se.version = fields[0]
+	case 0:
+		return fmt.Errorf("missing minimal version of symbol '%s'", se.name)
+	default:
+		return fmt.Errorf("unexpected data after symbol '%s'", se.name)
+	}
+
+	return nil
+}
+
+// SymbolsLibrary is a block of a symbols file, describing symbols of one library:
+//
+//	libfoo.so.1 libfoo1 #MINVER#
+//	| libfoo1-alt #MINVER#
+//	* Build-Depends-Package: libfoo-dev
+//	 foo@Base 1.0
+type SymbolsLibrary struct {
+	soname       string
+	dependencies []string
+	meta         map[string]string
+	metaNames    []string
+	symbols      []SymbolElement
+	index        map[string]int
+}
+
+// NewSymbolsLibrary constructor
+func NewSymbolsLibrary() *SymbolsLibrary {
+	sl := new(SymbolsLibrary)
+	sl.dependencies = make([]string, 0)
+	sl.meta = make(map[string]string)
+	sl.metaNames = make([]string, 0)
+	sl.symbols = make([]SymbolElement, 0)
+	return sl
+}
+
+// Soname returns the SONAME of the library, e.g. libfoo.so.1
+func (sl *SymbolsLibrary) Soname() string {
+	return sl.soname
+}
+
+// Dependency returns the main dependency template, e.g. "libfoo1 #MINVER#".
+func (sl *SymbolsLibrary) Dependency() string {
+	return sl.Template(0)
+}
+
+// Dependencies returns all dependency templates. The first one is the main
+// template, the rest are alternative templates, referred by symbols by index.
+func (sl *SymbolsLibrary) Dependencies() []string {
+	return sl.dependencies
+}
+
+// Template returns dependency template by its ID, or an empty string if there is none.
+func (sl *SymbolsLibrary) Template(id int) string {
+	if id < 0 || id >= len(sl.dependencies) {
+		return ""
+	}
+	return sl.dependencies[id]
+}
+
+// DependencyFor returns the dependency template of a symbol, with #MINVER#
+// replaced with the minimal version of the symbol.
+func (sl *SymbolsLibrary) DependencyFor(se *SymbolElement) string {
+	tpl := sl.Template(se.depIndex)
+	if tpl == "" {
+		tpl = sl.Dependency()
+	}
+	minver := ""
+	if se.version != "" && se.version != "0" {
+		minver = "(>= " + se.version + ")"
+	}
+	return strings.TrimSpace(strings.Replace(tpl, "#MINVER#", minver, -1))
+}
+
+// MetaFields returns meta-information field names in order of appearance.
+func (sl *SymbolsLibrary) MetaFields() []string {
+	return sl.metaNames
+}
+
+// Meta returns value of a meta-information field, such as Build-Depends-Package.
+func (sl *SymbolsLibrary) Meta(name string) string {
+	return sl.meta[strings.ToLower(name)]
+}
+
+// BuildDependsPackage returns the name of the development package of the library.
+func (sl *SymbolsLibrary) BuildDependsPackage() string {
+	if pkg := sl.Meta("Build-Depends-Package"); pkg != "" {
+		return pkg
+	}
+	return sl.Meta("Build-Depends-Packages")
+}
+
+// Symbols returns symbols of the library.
+func (sl *SymbolsLibrary) Symbols() []SymbolElement {
+	return sl.symbols
+}
+
+// Lookup returns a symbol matching an ELF symbol name and version.
+// Plain symbols take precedence over regular expressions.
+func (sl *SymbolsLibrary) Lookup(name string, version string) *SymbolElement {
+	if sl.index == nil {
+		sl.index = make(map[string]int)
+		for idx := range sl.symbols {
+			if !sl.symbols[idx].IsRegex() && !sl.symbols[idx].IsCpp() {
+				sl.index[sl.symbols[idx].name] = idx
+			}
+		}
+	}
+	if version == "" {
+		version = "Base"
+	}
+	if idx, ok := sl.index[name+"@"+version]; ok {
+		return &sl.symbols[idx]
+	}
+	for idx := range sl.symbols {
+		if sl.symbols[idx].IsRegex() && sl.symbols[idx].Matches(name, version) {
+			return &sl.symbols[idx]
+		}
+	}
+	return nil
+}
+
 // part of the shlibdeps
 type SymbolsFile struct {
-	data []SymbolElement
+	libraries []*SymbolsLibrary
+	includes  []string
+	errors    []error
 }
 
 func NewSymbolsFile() *SymbolsFile {
 	smb := new(SymbolsFile)
-	smb.data = make([]SymbolElement, 0)
+	smb.libraries = make([]*SymbolsLibrary, 0)
+	smb.includes = make([]string, 0)
+	smb.errors = make([]error, 0)
 	return smb
 }
 
-// Parse symbols data
+// Parse symbols data. Malformed lines are skipped and collected as errors,
+// the first of them is returned.
 func (smb *SymbolsFile) parse(data []byte) error {
-	var line string
-	var elm []string
+	var lib *SymbolsLibrary
+	lineno := 0
+	fail := func(format string, args ...interface{}) {
+		smb.errors = append(smb.errors, &ParseError{File: "symbols", Line: lineno, Err: fmt.Sprintf(format, args...)})
+	}
+
 	scn := bufio.NewScanner(strings.NewReader(string(data)))
 	for scn.Scan() {
-		line = strings.TrimSpace(scn.Text())
-		if line != "" {
-			elm = strings.SplitN(line, " ", 2)
+		lineno++
+		line := strings.TrimRight(scn.Text(), " \t\r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			continue
+		case strings.HasPrefix(line, "#include"):
+			smb.includes = append(smb.includes, strings.Trim(strings.TrimSpace(line[8:]), "\""))
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "|"):
+			if lib == nil {
+				fail("alternative dependency template outside of a library block")
+				continue
+			}
+			lib.dependencies = append(lib.dependencies, strings.TrimSpace(line[1:]))
+		case strings.HasPrefix(line, "*"):
+			if lib == nil {
+				fail("meta-information field outside of a library block")
+				continue
+			}
+			nv := strings.SplitN(line[1:], ":", 2)
+			if len(nv) != 2 || strings.TrimSpace(nv[0]) == "" {
+				fail("malformed meta-information field")
+				continue
+			}
+			name := strings.TrimSpace(nv[0])
+			if _, ok := lib.meta[strings.ToLower(name)]; !ok {
+				lib.metaNames = append(lib.metaNames, name)
+			}
+			lib.meta[strings.ToLower(name)] = strings.TrimSpace(nv[1])
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			if lib == nil {
+				fail("symbol outside of a library block")
+				continue
+			}
 			se := NewSymbolElement()
-			se.base = elm[0]
-			se.version = elm[1]
-			smb.data = append(smb.data, *se)
+			if err := se.parse(strings.TrimSpace(line)); err != nil {
+				fail("%s", err.Error())
+				continue
+			}
+			lib.symbols = append(lib.symbols, *se)
+		default:
+			nd := strings.SplitN(line, " ", 2)
+			if len(nd) != 2 || strings.TrimSpace(nd[1]) == "" {
+				fail("library '%s' has no dependency template", nd[0])
+				lib = nil
+				continue
+			}
+			lib = NewSymbolsLibrary()
+			lib.soname = nd[0]
+			lib.dependencies = append(lib.dependencies, strings.TrimSpace(nd[1]))
+			smb.libraries = append(smb.libraries, lib)
 		}
 	}
+
+	if len(smb.errors) > 0 {
+		return smb.errors[0]
+	}
 	return nil
 }
 
-// GetSymbols returns parsed symbols file data
+// Libraries returns all library blocks of the symbols file
+func (smb *SymbolsFile) Libraries() []*SymbolsLibrary {
+	return smb.libraries
+}
+
+// Library returns a library block by its SONAME, or nil.
+func (smb *SymbolsFile) Library(soname string) *SymbolsLibrary {
+	for _, lib := range smb.libraries {
+		if lib.soname == soname {
+			return lib
+		}
+	}
+	return nil
+}
+
+// Includes returns files referred by #include directives.
+func (smb *SymbolsFile) Includes() []string {
+	return smb.includes
+}
+
+// Errors returns syntax errors found while parsing.
+func (smb *SymbolsFile) Errors() []error {
+	return smb.errors
+}
+
+// GetSymbols returns symbols of all libraries in the symbols file
 func (smb *SymbolsFile) GetSymbols() []SymbolElement {
-	return smb.data
+	symbols := make([]SymbolElement, 0)
+	for _, lib := range smb.libraries {
+		symbols = append(symbols, lib.symbols...)
+	}
+	return symbols
 }
@@ -0,0 +1,30 @@
+package deb
+
+import "testing"
+
+func TestSymbolsLookupRegex(t *testing.T) {
+	smb := NewSymbolsFile()
+	data := "libfoo.so.1 libfoo1 #MINVER#\n" +
+		" foo@Base 1.0\n" +
+		" (regex)\"^bar_.*@Base$\" 1.1\n" +
+		" (regex)\"^baz_(@Base$\" 1.2\n"
+	if err := smb.parse([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	lib := smb.Library("libfoo.so.1")
+	if lib == nil {
+		t.Fatal("library libfoo.so.1 is missing")
+	}
+	if se := lib.Lookup("foo", ""); se == nil || se.Version() != "1.0" {
+		t.Errorf("foo@Base: %+v", se)
+	}
+	if se := lib.Lookup("bar_x", "Base"); se == nil || se.Version() != "1.1" {
+		t.Errorf("bar_x@Base: %+v", se)
+	}
+	if se := lib.Lookup("bar_x", "FOO_1"); se != nil {
+		t.Errorf("bar_x@FOO_1 matched %s", se.Name())
+	}
+	if se := lib.Lookup("baz_(", ""); se != nil {
+		t.Errorf("invalid pattern matched %s", se.Name())
+	}
+}
@@ -0,0 +1,239 @@
+package deb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DebconfTemplate is a question or a note of a debconf templates file.
+type DebconfTemplate struct {
+	name         string
+	qtype        string
+	defaultValue string
+	choices      []string
+	choicesC     []string
+	description  string
+	extended     string
+	descriptions map[string]string
+	lchoices     map[string][]string
+}
+
+// NewDebconfTemplate constructor
+func NewDebconfTemplate() *DebconfTemplate {
+	dt := new(DebconfTemplate)
+	dt.choices = make([]string, 0)
+	dt.choicesC = make([]string, 0)
+	dt.descriptions = make(map[string]string)
+	dt.lchoices = make(map[string][]string)
+	return dt
+}
+
+// Name returns the template name, e.g. "hello/greeting".
+func (dt *DebconfTemplate) Name() string {
+	return dt.name
+}
+
+// Type returns the template type: string, password, boolean, select,
+// multiselect, note, text, error or title.
+func (dt *DebconfTemplate) Type() string {
+	return dt.qtype
+}
+
+// IsQuestion returns true if the template expects an answer, rather than
+// only displaying information.
+func (dt *DebconfTemplate) IsQuestion() bool {
+	return !in(dt.qtype, []string{"note", "text", "error", "title"})
+}
+
+// Default returns the default answer.
+func (dt *DebconfTemplate) Default() string {
+	return dt.defaultValue
+}
+
+// Choices returns choices of select and multiselect templates.
+func (dt *DebconfTemplate) Choices() []string {
+	return dt.choices
+}
+
+// Values returns the values that can be answered to a select or
+// multiselect template. These are the untranslated Choices-C if the template
+// has them, or the Choices otherwise.
+func (dt *DebconfTemplate) Values() []string {
+	if len(dt.choicesC) > 0 {
+		return dt.choicesC
+	}
+	return dt.choices
+}
+
+// Description returns the short description of the template.
+func (dt *DebconfTemplate) Description() string {
+	return dt.description
+}
+
+// ExtendedDescription returns the long description of the template.
+// Paragraphs are separated with an empty line.
+func (dt *DebconfTemplate) ExtendedDescription() string {
+	return dt.extended
+}
+
+// Languages returns sorted languages the template is translated to, e.g. "de" or "pt_BR".
+func (dt *DebconfTemplate) Languages() []string {
+	langs := make([]string, 0, len(dt.descriptions))
+	for lang := range dt.descriptions {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// LocalizedDescription returns the short and extended description in a language.
+// Untranslated descriptions are returned if there is no translation.
+func (dt *DebconfTemplate) LocalizedDescription(lang string) (string, string) {
+	if desc, ok := dt.descriptions[lang]; ok {
+		return dt.splitDescription(desc)
+	}
+	return dt.description, dt.extended
+}
+
+// LocalizedChoices returns choices in a language, or untranslated ones
+// if there is no translation.
+func (dt *DebconfTemplate) LocalizedChoices(lang string) []string {
+	if choices, ok := dt.lchoices[lang]; ok {
+		return choices
+	}
+	return dt.choices
+}
+
+// Split Description field value to short and extended description
+func (dt *DebconfTemplate) splitDescription(desc string) (string, string) {
+	lines := strings.Split(desc, "\n")
+	for idx := 1; idx < len(lines); idx++ {
+		lines[idx] = strings.TrimSpace(lines[idx])
+		if lines[idx] == "." {
+			lines[idx] = ""
+		}
+	}
+	return strings.TrimSpace(lines[0]), strings.Join(lines[1:], "\n")
+}
+
+// Split choices list, where commas can be escaped with a backslash
+func (dt *DebconfTemplate) splitChoices(value string) []string {
+	choices := make([]string, 0)
+	var current strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		switch {
+		case value[idx] == '\\' && idx+1 < len(value) && value[idx+1] == ',':
+			current.WriteByte(',')
+			idx++
+		case value[idx] == ',':
+			choices = append(choices, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteByte(value[idx])
+		}
+	}
+	if last := strings.TrimSpace(current.String()); last != "" || len(choices) > 0 {
+		choices = append(choices, last)
+	}
+	return choices
+}
+
+// Take template data from a templates file paragraph
+func (dt *DebconfTemplate) parse(p *Paragraph) error {
+	dt.name = p.Get("Template")
+	dt.qtype = p.Get("Type")
+	if dt.name == "" {
+		return fmt.Errorf("missing Template field")
+	}
+	if dt.qtype == "" {
+		return fmt.Errorf("template '%s' has no Type", dt.name)
+	}
+	dt.defaultValue = p.Get("Default")
+	if p.Has("Choices") {
+		dt.choices = dt.splitChoices(p.Get("Choices"))
+	}
+	if p.Has("Choices-C") {
+		dt.choicesC = dt.splitChoices(p.Get("Choices-C"))
+	}
+	dt.description, dt.extended = dt.splitDescription(p.Get("Description"))
+
+	for _, name := range p.Names() {
+		nl := strings.SplitN(name, "-", 2)
+		if len(nl) != 2 || strings.EqualFold(nl[1], "C") {
+			continue
+		}
+		lang := nl[1]
+		if idx := strings.Index(lang, "."); idx > -1 {
+			lang = lang[:idx] // Encoding suffix, which is always UTF-8 nowadays
+		}
+		switch strings.ToLower(nl[0]) {
+		case "description":
+			dt.descriptions[lang] = p.Get(name)
+		case "choices":
+			dt.lchoices[lang] = dt.splitChoices(p.Get(name))
+		}
+	}
+
+	return nil
+}
+
+// TemplatesFile is the debconf templates control file.
+type TemplatesFile struct {
+	templates []DebconfTemplate
+	errors    []error
+}
+
+// NewTemplatesFile constructor
+func NewTemplatesFile() *TemplatesFile {
+	tf := new(TemplatesFile)
+	tf.templates = make([]DebconfTemplate, 0)
+	tf.errors = make([]error, 0)
+	return tf
+}
+
+// Parse templates file. Invalid templates are skipped and collected as errors,
+// the first of them is returned.
+func (tf *TemplatesFile) parse(data []byte) error {
+	paragraphs, err := parseParagraphs(data)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			perr.File = "templates"
+		}
+		tf.errors = append(tf.errors, err)
+		return err
+	}
+	for _, p := range paragraphs {
+		dt := NewDebconfTemplate()
+		if err := dt.parse(p); err != nil {
+			tf.errors = append(tf.errors, fmt.Errorf("templates: %s", err.Error()))
+			continue
+		}
+		tf.templates = append(tf.templates, *dt)
+	}
+
+	if len(tf.errors) > 0 {
+		return tf.errors[0]
+	}
+	return nil
+}
+
+// Templates returns all templates in order of appearance.
+func (tf *TemplatesFile) Templates() []DebconfTemplate {
+	return tf.templates
+}
+
+// Get returns a template by its name, or nil.
+func (tf *TemplatesFile) Get(name string) *DebconfTemplate {
+	for idx := range tf.templates {
+		if tf.templates[idx].name == name {
+			return &tf.templates[idx]
+		}
+	}
+	return nil
+}
+
+// Errors returns errors found while parsing.
+func (tf *TemplatesFile) Errors() []error {
+	return tf.errors
+}
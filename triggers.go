@@ -3,11 +3,43 @@ package deb
 import (
 	"bufio"
 	"fmt"
+	"sort"
 	"strings"
 )
 
+// TriggerDirective is a directive of a triggers file, see deb-triggers(5).
+type TriggerDirective int
+
+const (
+	TRIGGER_INTEREST TriggerDirective = iota
+	TRIGGER_INTEREST_AWAIT
+	TRIGGER_INTEREST_NOAWAIT
+	TRIGGER_ACTIVATE
+	TRIGGER_ACTIVATE_AWAIT
+	TRIGGER_ACTIVATE_NOAWAIT
+)
+
+var triggerDirectives = map[string]TriggerDirective{
+	"interest":         TRIGGER_INTEREST,
+	"interest-await":   TRIGGER_INTEREST_AWAIT,
+	"interest-noawait": TRIGGER_INTEREST_NOAWAIT,
+	"activate":         TRIGGER_ACTIVATE,
+	"activate-await":   TRIGGER_ACTIVATE_AWAIT,
+	"activate-noawait": TRIGGER_ACTIVATE_NOAWAIT,
+}
+
+func (td TriggerDirective) String() string {
+	for name, directive := range triggerDirectives {
+		if directive == td {
+			return name
+		}
+	}
+	return fmt.Sprintf("unknown(%d)", int(td))
+}
+
 type Trigger struct {
 	directive string
+	kind      TriggerDirective
 	name      string
 }
 
@@ -15,39 +47,100 @@ func NewTrigger() *Trigger {
 	return new(Trigger)
 }
 
+// Directive returns the directive as written in the triggers file.
 func (t *Trigger) Directive() string {
 	return t.directive
 }
 
+// Kind returns the directive of the trigger.
+func (t *Trigger) Kind() TriggerDirective {
+	return t.kind
+}
+
+// Name returns the trigger name, which is an absolute path for file triggers.
 func (t *Trigger) Name() string {
 	return t.name
 }
 
+// IsFileTrigger returns true if the trigger name is a path, activated by
+// packages shipping files at or below it.
+func (t *Trigger) IsFileTrigger() bool {
+	return strings.HasPrefix(t.name, "/")
+}
+
+// IsInterest returns true for interest directives.
+func (t *Trigger) IsInterest() bool {
+	return t.kind == TRIGGER_INTEREST || t.kind == TRIGGER_INTEREST_AWAIT || t.kind == TRIGGER_INTEREST_NOAWAIT
+}
+
+// IsActivate returns true for activate directives.
+func (t *Trigger) IsActivate() bool {
+	return !t.IsInterest()
+}
+
+// Await returns true if the triggering package waits until the trigger is processed.
+func (t *Trigger) Await() bool {
+	return t.kind != TRIGGER_INTEREST_NOAWAIT && t.kind != TRIGGER_ACTIVATE_NOAWAIT
+}
+
+// Parse and validate directive and trigger name
+func (t *Trigger) parse(directive string, name string) error {
+	kind, ok := triggerDirectives[directive]
+	if !ok {
+		return fmt.Errorf("unknown trigger directive '%s'", directive)
+	}
+	if name == "" {
+		return fmt.Errorf("missing trigger name for directive '%s'", directive)
+	}
+	for _, c := range name {
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("trigger name '%s' contains invalid characters", name)
+		}
+	}
+	t.directive, t.kind, t.name = directive, kind, name
+	return nil
+}
+
 type TriggerFile struct {
 	triggers []Trigger
+	errors   []error
 }
 
 func NewTriggerFile() *TriggerFile {
 	tf := new(TriggerFile)
 	tf.triggers = make([]Trigger, 0)
+	tf.errors = make([]error, 0)
 	return tf
 }
 
-// Parse triggers file
+// Parse triggers file. Invalid lines are skipped and collected as errors,
+// the first of them is returned.
 func (tf *TriggerFile) parse(data []byte) error {
+	lineno := 0
 	scn := bufio.NewScanner(strings.NewReader(string(data)))
 	for scn.Scan() {
-		line := strings.TrimSpace(scn.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			dn := strings.SplitN(strings.Split(line, "#")[0], " ", 2) // Trim comments
-			if len(dn) == 2 {
-				t := NewTrigger()
-				t.directive, t.name = dn[0], dn[1]
-				tf.triggers = append(tf.triggers, *t)
-			} else {
-				return fmt.Errorf("Could not parse name and directive in '%v' line.", line)
-			}
+		lineno++
+		fields := strings.Fields(strings.SplitN(scn.Text(), "#", 2)[0]) // Trim comments
+		if len(fields) == 0 {
+			continue
+		}
+		t := NewTrigger()
+		var err error
+		if len(fields) > 2 {
+			err = fmt.Errorf("unexpected data after trigger name '%s'", fields[1])
+		} else {
+			fields = append(fields, "")
+			err = t.parse(fields[0], fields[1])
 		}
+		if err != nil {
+			tf.errors = append(tf.errors, &ParseError{File: "triggers", Line: lineno, Err: err.Error()})
+			continue
+		}
+		tf.triggers = append(tf.triggers, *t)
+	}
+
+	if len(tf.errors) > 0 {
+		return tf.errors[0]
 	}
 	return nil
 }
@@ -56,3 +149,99 @@ func (tf *TriggerFile) parse(data []byte) error {
 func (tf TriggerFile) Triggers() []Trigger {
 	return tf.triggers
 }
+
+// Interests returns triggers the package is interested in.
+func (tf TriggerFile) Interests() []Trigger {
+	return tf.filter(true)
+}
+
+// Activations returns triggers the package activates explicitly.
+func (tf TriggerFile) Activations() []Trigger {
+	return tf.filter(false)
+}
+
+func (tf TriggerFile) filter(interest bool) []Trigger {
+	triggers := make([]Trigger, 0)
+	for _, t := range tf.triggers {
+		if t.IsInterest() == interest {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+// Errors returns validation errors found while parsing.
+func (tf TriggerFile) Errors() []error {
+	return tf.errors
+}
+
+// TriggerActivation is a trigger of an interested package that is activated
+// by installing another package.
+type TriggerActivation struct {
+	pkg     string
+	trigger Trigger
+	paths   []string
+	await   bool
+}
+
+// Package returns the name of the interested package.
+func (ta *TriggerActivation) Package() string {
+	return ta.pkg
+}
+
+// Trigger returns the interest trigger of the interested package.
+func (ta *TriggerActivation) Trigger() Trigger {
+	return ta.trigger
+}
+
+// Paths returns files that activate a file trigger.
+func (ta *TriggerActivation) Paths() []string {
+	return ta.paths
+}
+
+// Await returns true if the activating package waits for the trigger processing.
+func (ta *TriggerActivation) Await() bool {
+	return ta.await
+}
+
+// ActivatedTriggers returns triggers of packages in pkgs, which are activated
+// by the files and explicit activate directives of the package pf.
+// File triggers can only be matched if the files of pf were processed.
+func ActivatedTriggers(pf *PackageFile, pkgs []*PackageFile) []TriggerActivation {
+	paths := make([]string, 0, len(pf.Files()))
+	for _, fi := range pf.Files() {
+		if path := absPath(fi.Name()); path != "/" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	activations := make([]TriggerActivation, 0)
+	for _, other := range pkgs {
+		for _, interest := range other.TriggersFile().Interests() {
+			ta := TriggerActivation{pkg: other.ControlFile().Package(), trigger: interest, paths: make([]string, 0)}
+			if interest.IsFileTrigger() {
+				prefix := strings.TrimSuffix(interest.Name(), "/")
+				for _, path := range paths {
+					if path == prefix || strings.HasPrefix(path, prefix+"/") {
+						ta.paths = append(ta.paths, path)
+					}
+				}
+				ta.await = interest.Await()
+				if len(ta.paths) > 0 {
+					activations = append(activations, ta)
+				}
+				continue
+			}
+			for _, activate := range pf.TriggersFile().Activations() {
+				if activate.Name() == interest.Name() {
+					ta.await = interest.Await() && activate.Await()
+					activations = append(activations, ta)
+					break
+				}
+			}
+		}
+	}
+
+	return activations
+}
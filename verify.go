@@ -0,0 +1,173 @@
+package deb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Checksum of the package file, calculated while reading it. The file is
+// not read again, as it may have changed since, or be remote.
+func (c *PackageFile) packageChecksum(hash int) (string, error) {
+	if len(c.packageChecksums) == 0 {
+		return "", fmt.Errorf("checksum of the package is not available, it must be read fully, not only its meta-data")
+	}
+	if sum := c.packageChecksums[hash]; sum != "" {
+		return sum, nil
+	}
+	return "", fmt.Errorf("%s checksum of the package was not calculated, add it to the hashes of the package options", HashName(hash))
+}
+
+// VerifyPackage checks that a package is the one of the stanza: its name,
+// version and architecture, and the Size and SHA256 of the package file.
+func (ie *IndexEntry) VerifyPackage(pf *PackageFile) error {
+	cf := pf.ControlFile()
+	if cf.Package() != ie.Package() || cf.Version() != ie.Version() || cf.Architecture() != ie.Architecture() {
+		return fmt.Errorf("package %s %s [%s] does not match %s %s [%s]", cf.Package(), cf.Version(), cf.Architecture(),
+			ie.Package(), ie.Version(), ie.Architecture())
+	}
+	if ie.Field("Size") == "" || ie.Field("SHA256") == "" {
+		return fmt.Errorf("%s: stanza has no Size or SHA256", ie.Package())
+	}
+	if ie.Size() != int64(pf.FileSize()) {
+		return fmt.Errorf("%s: size %d, expected %d", ie.Package(), pf.FileSize(), ie.Size())
+	}
+	sum, err := pf.packageChecksum(HASH_SHA256)
+	if err != nil {
+		return fmt.Errorf("%s: %s", ie.Package(), err.Error())
+	}
+	if !strings.EqualFold(sum, ie.Field("SHA256")) {
+		return fmt.Errorf("%s: SHA256 %s, expected %s", ie.Package(), sum, ie.Field("SHA256"))
+	}
+	return nil
+}
+
+// VerifyOptions configure verification of Release files.
+type VerifyOptions struct {
+	// Time to check expiry against. The current time is used if zero.
+	Time time.Time
+
+	// Maximal age of the Release file by its Date, if not zero. Valid-Until
+	// is always checked.
+	MaxAge time.Duration
+}
+
+var DefaultVerifyOptions = &VerifyOptions{}
+
+// VerifiedIndex is a Packages index verified with a signed Release file,
+// following the chain of trust of APT.
+type VerifiedIndex struct {
+	release *ReleaseFile
+	index   *PackagesIndex
+	signer  *openpgp.Entity
+}
+
+// VerifyIndex checks a Release file signed by a key of the keyring, and a
+// Packages index listed in it, given its path relative to the suite, e.g.
+// "main/binary-amd64/Packages.xz". The Release file is inline-signed
+// InRelease if the signature is nil, or Release with the detached
+// signature of Release.gpg otherwise. Expired Release files are rejected,
+// see VerifyOptions; DefaultVerifyOptions are used if opts is nil.
+func VerifyIndex(keyring io.Reader, release []byte, signature []byte, path string, index []byte, opts *VerifyOptions) (*VerifiedIndex, error) {
+	if opts == nil {
+		opts = DefaultVerifyOptions
+	}
+	now := opts.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	vi := new(VerifiedIndex)
+	var err error
+	if signature == nil {
+		release, vi.signer, err = VerifyInRelease(release, keyring)
+	} else {
+		vi.signer, err = VerifyRelease(release, signature, keyring)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if vi.release, err = ParseReleaseFile(release); err != nil {
+		return nil, err
+	}
+	if err := vi.release.CheckValid(now, opts.MaxAge); err != nil {
+		return nil, err
+	}
+	if err := vi.release.VerifyFile(path, index); err != nil {
+		return nil, err
+	}
+
+	r, err := indexReader(bytes.NewReader(index), path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if vi.index, err = ParsePackagesIndex(r); err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			perr.File = path
+		}
+		return nil, err
+	}
+	return vi, nil
+}
+
+// OpenVerifiedIndex verifies an index of a suite directory of a repository
+// or mirror, e.g. "dists/stable", using InRelease, or Release and
+// Release.gpg. If the index is missing, its compressed variants are used.
+func OpenVerifiedIndex(keyring io.Reader, suiteDir string, path string, opts *VerifyOptions) (*VerifiedIndex, error) {
+	var release, signature []byte
+	var err error
+	if release, err = ioutil.ReadFile(filepath.Join(suiteDir, "InRelease")); os.IsNotExist(err) {
+		if release, err = ioutil.ReadFile(filepath.Join(suiteDir, "Release")); err != nil {
+			return nil, err
+		}
+		signature, err = ioutil.ReadFile(filepath.Join(suiteDir, "Release.gpg"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var index []byte
+	for _, name := range []string{path, path + ".xz", path + ".gz"} {
+		if index, err = ioutil.ReadFile(filepath.Join(suiteDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			path = name
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return VerifyIndex(keyring, release, signature, path, index, opts)
+}
+
+// Release returns the verified Release file.
+func (vi *VerifiedIndex) Release() *ReleaseFile {
+	return vi.release
+}
+
+// Index returns the verified Packages index.
+func (vi *VerifiedIndex) Index() *PackagesIndex {
+	return vi.index
+}
+
+// Signer returns the owner of the key which signed the Release file.
+func (vi *VerifiedIndex) Signer() *openpgp.Entity {
+	return vi.signer
+}
+
+// VerifyPackage checks a package against its stanza of the index, see
+// IndexEntry.VerifyPackage. It returns the stanza.
+func (vi *VerifiedIndex) VerifyPackage(pf *PackageFile) (*IndexEntry, error) {
+	cf := pf.ControlFile()
+	for _, ie := range vi.index.Entries() {
+		if ie.Package() == cf.Package() && ie.Version() == cf.Version() && ie.Architecture() == cf.Architecture() {
+			return ie, ie.VerifyPackage(pf)
+		}
+	}
+	return nil, fmt.Errorf("package %s %s [%s] is not in the index", cf.Package(), cf.Version(), cf.Architecture())
+}
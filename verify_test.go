@@ -0,0 +1,157 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Stanza of a package file as in a Packages index
+func testIndexEntry(t *testing.T, debPath string) *IndexEntry {
+	data, err := os.ReadFile(debPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	return NewIndexEntry().SetField("Package", "hello").SetField("Version", "1.0-1").SetField("Architecture", "amd64").
+		SetField("Filename", "pool/main/h/hello/hello_1.0-1_amd64.deb").SetField("Size", strconv.Itoa(len(data))).
+		SetField("SHA256", hex.EncodeToString(sum[:]))
+}
+
+func TestVerifyPackageDefaultOptions(t *testing.T) {
+	debPath := testPackagePath(t, t.TempDir(), testBuilder())
+	pf, err := OpenPackageFile(debPath, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testIndexEntry(t, debPath).VerifyPackage(pf); err != nil {
+		t.Error(err)
+	}
+
+	pf, err = OpenPackageFile(debPath, &PackageOptions{Hash: HASH_MD5, MetaOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testIndexEntry(t, debPath).VerifyPackage(pf); err == nil {
+		t.Errorf("package verified with meta-data only")
+	}
+}
+
+// Signed Release file listing a Packages index
+type testSignedIndex struct {
+	release  []byte
+	detached []byte
+	inline   []byte
+	index    []byte
+}
+
+// Sign a Release file of a Date, listing an index with a hash field
+func testSign(t *testing.T, key *openpgp.Entity, date time.Time, validUntil time.Time, field string, index []byte) *testSignedIndex {
+	rf := NewReleaseFile().SetField("Origin", "Test").SetField("Suite", "stable").SetField("Date", date.Format(time.RFC1123))
+	if !validUntil.IsZero() {
+		rf.SetField("Valid-Until", validUntil.Format(time.RFC1123))
+	}
+	h := newHash(releaseHashTypes[field])
+	h.Write(index)
+	rf.AddChecksum(field, "main/binary-amd64/Packages", int64(len(index)), hex.EncodeToString(h.Sum(nil)))
+	si := &testSignedIndex{release: rf.Bytes(), index: index}
+	var err error
+	if si.detached, si.inline, err = SignRelease(si.release, key); err != nil {
+		t.Fatal(err)
+	}
+	return si
+}
+
+func TestVerifyIndex(t *testing.T) {
+	key := testKey(t, time.Now().Add(-time.Hour), 0)
+	other := testKey(t, time.Now().Add(-time.Hour), 0)
+	date := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	debPath := testPackagePath(t, t.TempDir(), testBuilder())
+	index := NewPackagesIndex().Add(testIndexEntry(t, debPath)).Bytes()
+	tampered := bytes.Replace(index, []byte("Version: 1.0-1"), []byte("Version: 1.0-2"), 1)
+
+	tests := []struct {
+		name     string
+		signed   *testSignedIndex
+		detached bool
+		index    []byte
+		opts     *VerifyOptions
+		err      string
+	}{
+		{"good InRelease", testSign(t, key, date, time.Time{}, "SHA256", index), false, index, nil, ""},
+		{"good Release.gpg", testSign(t, key, date, time.Time{}, "SHA256", index), true, index, nil, ""},
+		{"good SHA512", testSign(t, key, date, time.Time{}, "SHA512", index), false, index, nil, ""},
+		{"valid until later", testSign(t, key, date, date.Add(24*time.Hour), "SHA256", index), false, index, nil, ""},
+		{"wrong key InRelease", testSign(t, other, date, time.Time{}, "SHA256", index), false, index, nil, "signature"},
+		{"wrong key Release.gpg", testSign(t, other, date, time.Time{}, "SHA256", index), true, index, nil, "signature"},
+		{"expired", testSign(t, key, date, date.Add(time.Minute), "SHA256", index), false, index, nil, "expired"},
+		{"expired at a time", testSign(t, key, date, date.Add(24*time.Hour), "SHA256", index), false, index,
+			&VerifyOptions{Time: date.Add(48 * time.Hour)}, "expired"},
+		{"older than max age", testSign(t, key, date, time.Time{}, "SHA256", index), false, index,
+			&VerifyOptions{Time: date.Add(48 * time.Hour), MaxAge: 24 * time.Hour}, "older than"},
+		{"within max age", testSign(t, key, date, time.Time{}, "SHA256", index), false, index,
+			&VerifyOptions{MaxAge: 24 * time.Hour}, ""},
+		{"MD5 only", testSign(t, key, date, time.Time{}, "MD5Sum", index), false, index, nil, "SHA256 is required"},
+		{"SHA1 only", testSign(t, key, date, time.Time{}, "SHA1", index), false, index, nil, "SHA256 is required"},
+		{"index hash mismatch", testSign(t, key, date, time.Time{}, "SHA256", index), false, tampered, nil, "SHA256"},
+		{"index size mismatch", testSign(t, key, date, time.Time{}, "SHA256", index), false, append(index, '\n'), nil, "size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release, signature := tt.signed.inline, []byte(nil)
+			if tt.detached {
+				release, signature = tt.signed.release, tt.signed.detached
+			}
+			vi, err := VerifyIndex(bytes.NewReader(testPublicKeyring(t, key)), release, signature, "main/binary-amd64/Packages", tt.index, tt.opts)
+			checkSignError(t, "index", err, tt.err)
+			if err != nil {
+				return
+			}
+			if len(vi.Index().Entries()) != 1 || vi.Release().Suite() != "stable" {
+				t.Errorf("%d index entries of suite %q", len(vi.Index().Entries()), vi.Release().Suite())
+			}
+		})
+	}
+}
+
+func TestVerifiedIndexVerifyPackage(t *testing.T) {
+	key := testKey(t, time.Now().Add(-time.Hour), 0)
+	date := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	debPath := testPackagePath(t, t.TempDir(), testBuilder())
+	pf, err := OpenPackageFile(debPath, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5sum := md5.Sum([]byte("hello"))
+
+	tests := []struct {
+		name  string
+		entry *IndexEntry
+		err   string
+	}{
+		{"good package", testIndexEntry(t, debPath), ""},
+		{"size mismatch", testIndexEntry(t, debPath).SetField("Size", strconv.FormatUint(pf.FileSize()+1, 10)), "size"},
+		{"hash mismatch", testIndexEntry(t, debPath).SetField("SHA256", strings.Repeat("0", 64)), "SHA256"},
+		{"MD5 only", testIndexEntry(t, debPath).SetField("SHA256", "").SetField("MD5sum", hex.EncodeToString(md5sum[:])), "no Size or SHA256"},
+		{"other version", testIndexEntry(t, debPath).SetField("Version", "1.0-2"), "not in the index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := testSign(t, key, date, time.Time{}, "SHA256", NewPackagesIndex().Add(tt.entry).Bytes())
+			vi, err := VerifyIndex(bytes.NewReader(testPublicKeyring(t, key)), signed.inline, nil, "main/binary-amd64/Packages", signed.index, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = vi.VerifyPackage(pf)
+			checkSignError(t, "package", err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package deb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version of a Debian package: [epoch:]upstream_version[-debian_revision]
+type Version struct {
+	epoch    int
+	upstream string
+	revision string
+}
+
+// ParseVersion parses and validates a version string according to the Debian policy.
+func ParseVersion(version string) (*Version, error) {
+	v := new(Version)
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return nil, fmt.Errorf("version string is empty")
+	}
+	if strings.ContainsAny(version, " \t") {
+		return nil, fmt.Errorf("version string '%s' has embedded spaces", version)
+	}
+
+	if idx := strings.Index(version, ":"); idx > -1 {
+		epoch, err := strconv.Atoi(version[:idx])
+		if err != nil || epoch < 0 {
+			return nil, fmt.Errorf("epoch in version '%s' is not a number", version)
+		}
+		v.epoch, version = epoch, version[idx+1:]
+	}
+
+	v.upstream = version
+	if idx := strings.LastIndex(version, "-"); idx > -1 {
+		v.upstream, v.revision = version[:idx], version[idx+1:]
+		if v.revision == "" {
+			return nil, fmt.Errorf("revision number is empty")
+		}
+	}
+
+	if v.upstream == "" {
+		return nil, fmt.Errorf("version number is empty")
+	}
+	if v.upstream[0] < '0' || v.upstream[0] > '9' {
+		return nil, fmt.Errorf("version number '%s' does not start with digit", v.upstream)
+	}
+	for _, c := range v.upstream {
+		if !isVersionChar(c) && c != '-' && c != ':' {
+			return nil, fmt.Errorf("invalid character '%c' in version number", c)
+		}
+	}
+	for _, c := range v.revision {
+		if !isVersionChar(c) {
+			return nil, fmt.Errorf("invalid character '%c' in revision number", c)
+		}
+	}
+
+	return v, nil
+}
+
+// Characters allowed in upstream version and revision
+func isVersionChar(c rune) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || strings.ContainsRune(".+~", c)
+}
+
+// Epoch of the version
+func (v *Version) Epoch() int {
+	return v.epoch
+}
+
+// Upstream returns upstream part of the version
+func (v *Version) Upstream() string {
+	return v.upstream
+}
+
+// Revision returns Debian revision of the version
+func (v *Version) Revision() string {
+	return v.revision
+}
+
+func (v *Version) String() string {
+	version := v.upstream
+	if v.epoch > 0 {
+		version = strconv.Itoa(v.epoch) + ":" + version
+	}
+	if v.revision != "" {
+		version += "-" + v.revision
+	}
+	return version
+}
+
+// Compare returns -1, 0 or 1 if the version is lower, equal or greater than other.
+func (v *Version) Compare(other *Version) int {
+	if v.epoch != other.epoch {
+		if v.epoch < other.epoch {
+			return -1
+		}
+		return 1
+	}
+	if r := compareVersionPart(v.upstream, other.upstream); r != 0 {
+		return r
+	}
+	return compareVersionPart(v.revision, other.revision)
+}
+
+// CompareVersions compares two version strings the way dpkg does, returning
+// -1, 0 or 1. Versions that do not pass validation are still compared,
+// as dpkg does with versions it has already accepted.
+func CompareVersions(a string, b string) int {
+	return lenientVersion(a).Compare(lenientVersion(b))
+}
+
+// Split version string without validation
+func lenientVersion(version string) *Version {
+	if v, err := ParseVersion(version); err == nil {
+		return v
+	}
+	v := new(Version)
+	version = strings.TrimSpace(version)
+	if idx := strings.Index(version, ":"); idx > -1 {
+		v.epoch, _ = strconv.Atoi(version[:idx])
+		version = version[idx+1:]
+	}
+	v.upstream = version
+	if idx := strings.LastIndex(version, "-"); idx > -1 {
+		v.upstream, v.revision = version[:idx], version[idx+1:]
+	}
+	return v
+}
+
+// Sort weight of a character in non-digit parts of a version
+func versionOrder(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	case c == '~':
+		return -1
+	case c != 0:
+		return int(c) + 256
+	}
+	return 0
+}
+
+// Compare upstream or revision parts of version, see deb-version(7)
+func compareVersionPart(a string, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		diff := 0
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			var ac, bc int
+			if i < len(a) {
+				ac = versionOrder(a[i])
+			}
+			if j < len(b) {
+				bc = versionOrder(b[j])
+			}
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if diff == 0 {
+				diff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if diff != 0 {
+			return sign(diff)
+		}
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}